@@ -1,16 +1,5 @@
 package oachecker
 
-import (
-	"fmt"
-	vd "github.com/bytedance/go-tagexpr/v2/validator"
-)
-
-func init() {
-	vd.SetErrorFactory(func(failPath, msg string) error {
-		return fmt.Errorf(`"validation failed: %s","msg": "%s"`, failPath, msg)
-	})
-}
-
 type AppConfiguration struct {
 	ConfigVersion string        `yaml:"olaresManifest.version" json:"olaresManifest.version" vd:"len($)>0;msg:sprintf('invalid parameter: %v;olaresManifest.version must satisfy the expr: len($)>0',$)"`
 	ConfigType    string        `yaml:"olaresManifest.type" json:"olaresManifest.type"`
@@ -340,10 +329,14 @@ type Chart struct {
 	Version    string `yaml:"version"`
 }
 
+// TitleInfo is a PR title of the form [pr type][foldername][version]title,
+// see ParsePRTitle.
 type TitleInfo struct {
 	PrType  string
 	Folder  string
 	Version string
+	// Title is the free text after the bracketed parts.
+	Title string
 }
 
 type ServicePort struct {
@@ -0,0 +1,175 @@
+package oachecker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveLimits bound what is read from a chart archive.
+type ArchiveLimits struct {
+	// MaxFileSize is the largest size of a single file.
+	MaxFileSize int64
+	// MaxTotalSize is the largest size of all files together.
+	MaxTotalSize int64
+	// MaxEntries is the largest number of entries.
+	MaxEntries int
+}
+
+// DefaultArchiveLimits are the limits Helm applies when loading archives.
+var DefaultArchiveLimits = ArchiveLimits{
+	MaxFileSize:  5 << 20,
+	MaxTotalSize: 100 << 20,
+	MaxEntries:   10000,
+}
+
+// chartArchive is the content of a chart archive: the name of the chart
+// folder it holds and its files by slash separated path within that folder.
+type chartArchive struct {
+	name  string
+	files map[string][]byte
+}
+
+// readChartArchive reads a gzip compressed or plain tar archive as written by
+// helm package. Every entry must be a regular file or a folder below a single
+// top-level folder.
+func readChartArchive(r io.Reader, limits ArchiveLimits) (*chartArchive, error) {
+	br := bufio.NewReader(r)
+	var tr *tar.Reader
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrArchiveInvalid, err)
+		}
+		defer gz.Close()
+		tr = tar.NewReader(gz)
+	} else {
+		tr = tar.NewReader(br)
+	}
+
+	a := &chartArchive{files: make(map[string][]byte)}
+	var total int64
+	for entries := 0; ; entries++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrArchiveInvalid, err)
+		}
+		if entries >= limits.MaxEntries {
+			return nil, fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, limits.MaxEntries)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			continue
+		case tar.TypeDir, tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("%w: %s is not a regular file", ErrArchiveUnsafePath, hdr.Name)
+		}
+		top, rel, err := splitArchivePath(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		if a.name == "" {
+			a.name = top
+		} else if top != a.name {
+			return nil, fmt.Errorf("%w: entries in %s and %s", ErrArchiveInvalid, a.name, top)
+		}
+		if hdr.Typeflag == tar.TypeDir || rel == "" {
+			continue
+		}
+		if hdr.Size > limits.MaxFileSize {
+			return nil, fmt.Errorf("%w: %s has %d bytes", ErrArchiveTooLarge, hdr.Name, hdr.Size)
+		}
+		// the header may lie about the size, read at most one byte past it
+		data, err := io.ReadAll(io.LimitReader(tr, limits.MaxFileSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrArchiveInvalid, err)
+		}
+		if int64(len(data)) > limits.MaxFileSize {
+			return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrArchiveTooLarge, hdr.Name, limits.MaxFileSize)
+		}
+		total += int64(len(data))
+		if total > limits.MaxTotalSize {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, limits.MaxTotalSize)
+		}
+		if _, ok := a.files[rel]; ok {
+			return nil, fmt.Errorf("%w: duplicate entry %s", ErrArchiveInvalid, hdr.Name)
+		}
+		a.files[rel] = data
+	}
+	if a.name == "" {
+		return nil, fmt.Errorf("%w: no chart folder", ErrArchiveInvalid)
+	}
+	return a, nil
+}
+
+// splitArchivePath splits an entry name into its top-level folder and the
+// path below it, rejecting absolute paths and paths leaving the archive.
+func splitArchivePath(name string) (string, string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", "", fmt.Errorf("%w: %s", ErrArchiveUnsafePath, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", "", fmt.Errorf("%w: %s", ErrArchiveUnsafePath, name)
+		}
+	}
+	clean := path.Clean(name)
+	if clean == "." {
+		return "", "", fmt.Errorf("%w: %s", ErrArchiveUnsafePath, name)
+	}
+	top, rel, _ := strings.Cut(clean, "/")
+	return top, rel, nil
+}
+
+// mapFS returns the files of a as a file system holding the chart folder.
+func (a *chartArchive) mapFS() fs.FS {
+	files := make(map[string][]byte, len(a.files))
+	for name, data := range a.files {
+		files[path.Join(a.name, name)] = data
+	}
+	return newMemFS(files)
+}
+
+// LintArchive lints the chart archive at file, as produced by helm package.
+func LintArchive(file string, options *LintOptions) (*Report, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	report, err := LintArchiveContext(context.Background(), f, options)
+	if report != nil {
+		report.Path = file
+	}
+	return report, err
+}
+
+// LintArchiveReader lints a chart archive read from r.
+func LintArchiveReader(r io.Reader, options *LintOptions) (*Report, error) {
+	return LintArchiveContext(context.Background(), r, options)
+}
+
+// LintArchiveContext lints a chart archive read from r within
+// DefaultArchiveLimits, without writing it to disk. The error reports
+// archives that can not be read; problems of the chart are findings of the
+// report, which is named after the chart folder in the archive.
+func LintArchiveContext(ctx context.Context, r io.Reader, options *LintOptions) (*Report, error) {
+	a, err := readChartArchive(r, DefaultArchiveLimits)
+	if err != nil {
+		return nil, err
+	}
+	return LintReportFS(ctx, a.mapFS(), a.name, options), nil
+}
@@ -0,0 +1,190 @@
+package oachecker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// baselineVersion 2 leaves list indexes out of fingerprints.
+const baselineVersion = 2
+
+// Baseline is a recorded set of known findings. Lint runs with a baseline
+// only report findings that are not part of it.
+type Baseline struct {
+	Version int             `json:"version"`
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// BaselineEntry is one recorded finding. Findings are matched by
+// Fingerprint, which leaves out line and column so entries survive edits
+// moving the offending code around.
+type BaselineEntry struct {
+	RuleID      string `json:"ruleId"`
+	Chart       string `json:"chart"`
+	File        string `json:"file,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Message     string `json:"message"`
+}
+
+var indexRe = regexp.MustCompile(`\[[0-9]+\]`)
+
+// Fingerprint identifies a finding independently of its position in a file.
+// List indexes in the field path are left out, so reordering list entries,
+// e.g. entrances, keeps the fingerprints of their findings.
+func Fingerprint(f *Finding) string {
+	h := sha256.New()
+	path := indexRe.ReplaceAllString(f.Path, "[]")
+	message := strings.Join(strings.Fields(f.Message), " ")
+	for _, part := range []string{f.RuleID, f.Chart, f.File, path, f.Kind, f.Name, message} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// NewBaseline records the findings of reports.
+func NewBaseline(reports ...*Report) *Baseline {
+	b := &Baseline{Version: baselineVersion}
+	for _, r := range reports {
+		for _, f := range r.Findings {
+			b.Entries = append(b.Entries, BaselineEntry{
+				RuleID:      f.RuleID,
+				Chart:       f.Chart,
+				File:        f.File,
+				Fingerprint: Fingerprint(f),
+				Message:     f.Message,
+			})
+		}
+	}
+	b.sort()
+	return b
+}
+
+func (b *Baseline) sort() {
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		ei, ej := b.Entries[i], b.Entries[j]
+		if ei.Chart != ej.Chart {
+			return ei.Chart < ej.Chart
+		}
+		if ei.RuleID != ej.RuleID {
+			return ei.RuleID < ej.RuleID
+		}
+		return ei.Fingerprint < ej.Fingerprint
+	})
+}
+
+// LoadBaseline reads a baseline file written by Save or WriteBaseline.
+func LoadBaseline(file string) (*Baseline, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %v", file, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", b.Version, file)
+	}
+	return &b, nil
+}
+
+// Save writes b to file.
+func (b *Baseline) Save(file string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// WriteBaseline records the findings of reports in file.
+func WriteBaseline(file string, reports ...*Report) error {
+	return NewBaseline(reports...).Save(file)
+}
+
+// Filter removes the findings of report that are part of b and returns the
+// number of findings removed.
+func (b *Baseline) Filter(report *Report) int {
+	m := b.matcher(report.Chart)
+	var kept []*Finding
+	for _, f := range report.Findings {
+		if m.match(f) {
+			report.Baselined++
+			report.BaselinedFindings = append(report.BaselinedFindings, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	removed := len(report.Findings) - len(kept)
+	report.Findings = kept
+	return removed
+}
+
+// Prune drops the entries of the charts in reports that no longer match a
+// finding, i.e. problems that have been fixed, and returns how many entries
+// were dropped. Findings hidden by a baseline count as found, so reports of
+// runs with a baseline can be passed. Entries of charts without a report are
+// kept.
+func (b *Baseline) Prune(reports ...*Report) int {
+	current := make(map[string]map[string]int)
+	for _, r := range reports {
+		counts := make(map[string]int)
+		for _, f := range r.Findings {
+			counts[Fingerprint(f)]++
+		}
+		for _, f := range r.BaselinedFindings {
+			counts[Fingerprint(f)]++
+		}
+		current[r.Chart] = counts
+	}
+	var kept []BaselineEntry
+	for _, e := range b.Entries {
+		counts, ok := current[e.Chart]
+		if !ok {
+			kept = append(kept, e)
+			continue
+		}
+		if counts[e.Fingerprint] > 0 {
+			counts[e.Fingerprint]--
+			kept = append(kept, e)
+		}
+	}
+	pruned := len(b.Entries) - len(kept)
+	b.Entries = kept
+	return pruned
+}
+
+// baselineMatcher consumes baseline entries of one chart, so a finding
+// recorded once only hides one occurrence.
+type baselineMatcher struct {
+	counts map[string]int
+}
+
+func (b *Baseline) matcher(chart string) *baselineMatcher {
+	m := &baselineMatcher{counts: make(map[string]int)}
+	if b == nil {
+		return m
+	}
+	for _, e := range b.Entries {
+		if e.Chart == chart {
+			m.counts[e.Fingerprint]++
+		}
+	}
+	return m
+}
+
+func (m *baselineMatcher) match(f *Finding) bool {
+	fp := Fingerprint(f)
+	if m.counts[fp] > 0 {
+		m.counts[fp]--
+		return true
+	}
+	return false
+}
@@ -0,0 +1,371 @@
+package oachecker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chart"
+	helmLoader "helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// ChartBundle is a chart folder read, parsed and rendered once and shared by
+// every check of a run. The fields are filled as checks need them;
+// LoadChartBundle fills all of them up front.
+type ChartBundle struct {
+	// Path is the chart folder as given by the caller, a path on disk or
+	// within FS.
+	Path string
+	// FS holds the files of the chart folder at its root.
+	FS fs.FS
+	// Folder is the name of the chart folder.
+	Folder string
+	// ChartFile is the parsed Chart.yaml.
+	ChartFile *Chart
+	// Chart is the chart as loaded by Helm, with templates and values.
+	Chart *chart.Chart
+	// Values are the values of values.yaml.
+	Values map[string]interface{}
+	// RawManifest is OlaresManifest.yaml as written, RenderedManifest the
+	// result of rendering it with the fake owner/admin values and Manifest
+	// the parsed result.
+	RawManifest      []byte
+	RenderedManifest string
+	Manifest         *AppConfiguration
+	// I18n holds the raw OlaresManifest.yaml overlays under i18n/ by locale.
+	I18n map[string][]byte
+	// Owners are the GitHub logins listed in the owners file.
+	Owners Owners
+	// Resources are the resources of the Helm dry run.
+	Resources kube.ResourceList
+
+	// manifestOpts set the values OlaresManifest.yaml is rendered with,
+	// options those of the dry run.
+	manifestOpts []func(map[string]interface{})
+	options      *LintOptions
+	locator      *manifestLocator
+
+	chartFileLoaded bool
+	chartFileErr    error
+	manifestLoaded  bool
+	manifestErr     error
+	chartLoaded     bool
+	chartErr        error
+	rendered        bool
+	renderErr       error
+}
+
+// LoadChartBundle loads the chart folder at oacPath, renders it with the
+// owner, admin and values of options and returns the result. The error is
+// the first part that failed to load; the parts loaded before it are set.
+func LoadChartBundle(oacPath string, options *LintOptions) (*ChartBundle, error) {
+	return LoadChartBundleContext(context.Background(), oacPath, options)
+}
+
+// LoadChartBundleContext is LoadChartBundle with the Helm dry run bounded by
+// ctx.
+func LoadChartBundleContext(ctx context.Context, oacPath string, options *LintOptions) (*ChartBundle, error) {
+	if options == nil {
+		options = DefaultLintOptions()
+	}
+	b := newChartBundle(oacPath, options)
+	return b, b.load(ctx)
+}
+
+// load fills every part of b, stopping at the first that fails.
+func (b *ChartBundle) load(ctx context.Context) error {
+	if _, err := b.chartYaml(); err != nil {
+		return err
+	}
+	if _, err := b.appConfiguration(); err != nil {
+		return err
+	}
+	if _, err := b.helmChart(); err != nil {
+		return err
+	}
+	if err := b.loadI18n(); err != nil {
+		return err
+	}
+	if err := b.loadOwners(); err != nil {
+		return err
+	}
+	if _, err := b.resources(ctx); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// LoadChartBundleFS is LoadChartBundle for the chart folder dir of fsys.
+func LoadChartBundleFS(ctx context.Context, fsys fs.FS, dir string, options *LintOptions) (*ChartBundle, error) {
+	if options == nil {
+		options = DefaultLintOptions()
+	}
+	b, err := newChartBundleFS(fsys, dir, options)
+	if err != nil {
+		return b, err
+	}
+	return b, b.load(ctx)
+}
+
+// newChartBundle returns an empty bundle for the chart folder at oacPath,
+// rendering the manifest with the owner and admin of options.
+func newChartBundle(oacPath string, options *LintOptions) *ChartBundle {
+	b := newBundle(oacPath, options)
+	b.FS = os.DirFS(oacPath)
+	b.Folder = filepath.Base(filepath.Clean(oacPath))
+	return b
+}
+
+// newChartBundleFS returns an empty bundle for the chart folder dir of fsys.
+func newChartBundleFS(fsys fs.FS, dir string, options *LintOptions) (*ChartBundle, error) {
+	b := newBundle(dir, options)
+	b.Folder = path.Base(path.Clean(dir))
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return b, err
+	}
+	b.FS = sub
+	return b, nil
+}
+
+func newBundle(oacPath string, options *LintOptions) *ChartBundle {
+	return &ChartBundle{
+		Path:         oacPath,
+		manifestOpts: manifestOptions(options),
+		options:      options,
+	}
+}
+
+// manifestOptions are the render options of OlaresManifest.yaml for the
+// owner and admin of options.
+func manifestOptions(options *LintOptions) []func(map[string]interface{}) {
+	var opts []func(map[string]interface{})
+	if options != nil {
+		if options.Owner != "" {
+			opts = append(opts, WithOwner(options.Owner))
+		}
+		if options.Admin != "" {
+			opts = append(opts, WithAdmin(options.Admin))
+		}
+	}
+	return opts
+}
+
+// chartYaml reads and parses Chart.yaml. Failures are returned as findings.
+func (b *ChartBundle) chartYaml() (*Chart, error) {
+	if b.chartFileLoaded {
+		return b.ChartFile, b.chartFileErr
+	}
+	b.chartFileLoaded = true
+	content, err := fs.ReadFile(b.FS, "Chart.yaml")
+	if err != nil {
+		b.chartFileErr = newFinding(RuleChartYaml, "Chart.yaml",
+			chartErrorf(ErrReadChartYaml, b.Path, ReadChartYamlFailed, b.Path, err).withField("Chart.yaml").wrap(err))
+		return nil, b.chartFileErr
+	}
+	var c Chart
+	if err := yaml.Unmarshal(content, &c); err != nil {
+		b.chartFileErr = newFinding(RuleChartYaml, "Chart.yaml",
+			chartErrorf(ErrParseChartYaml, b.Path, ParseChartYamlFailed, b.Path, err).withField("Chart.yaml").wrap(err))
+		return nil, b.chartFileErr
+	}
+	b.ChartFile = &c
+	return b.ChartFile, nil
+}
+
+// appConfiguration reads, renders and parses OlaresManifest.yaml.
+func (b *ChartBundle) appConfiguration() (*AppConfiguration, error) {
+	if b.manifestLoaded {
+		return b.Manifest, b.manifestErr
+	}
+	b.manifestLoaded = true
+	content, err := fs.ReadFile(b.FS, ManifestName)
+	if err != nil {
+		b.manifestErr = err
+		return nil, err
+	}
+	b.RawManifest = content
+	rendered, err := RenderManifestFromContent(content, b.manifestOpts...)
+	if err != nil {
+		b.manifestErr = err
+		return nil, err
+	}
+	b.RenderedManifest = rendered
+	var cfg AppConfiguration
+	if err := yaml.Unmarshal([]byte(rendered), &cfg); err != nil {
+		b.manifestErr = err
+		return nil, err
+	}
+	b.Manifest = &cfg
+	b.locator = newManifestLocator(content, rendered)
+	return b.Manifest, nil
+}
+
+// helmChart loads the chart with Helm.
+func (b *ChartBundle) helmChart() (*chart.Chart, error) {
+	if b.chartLoaded {
+		return b.Chart, b.chartErr
+	}
+	b.chartLoaded = true
+	b.Chart, b.chartErr = loadChartFS(b.FS)
+	if b.Chart != nil {
+		b.Values = b.Chart.Values
+	}
+	return b.Chart, b.chartErr
+}
+
+// resources renders the chart once; later calls return the same result.
+// A render cut short by ctx is not kept.
+func (b *ChartBundle) resources(ctx context.Context) (kube.ResourceList, error) {
+	if b.rendered {
+		return b.Resources, b.renderErr
+	}
+	cfg, err := b.appConfiguration()
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.helmChart()
+	if err != nil {
+		return nil, err
+	}
+	resources, err := renderResources(ctx, c, cfg, b.options)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	b.rendered = true
+	b.Resources, b.renderErr = resources, err
+	return resources, err
+}
+
+// resourceFindings runs the checks on the rendered resources, the resource
+// stage of a lint run.
+func (b *ChartBundle) resourceFindings(ctx context.Context) []*Finding {
+	resources, err := b.resources(ctx)
+	if f := contextFinding(ctx); f != nil {
+		return []*Finding{f}
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return asFindings(err, RuleChartRender, "")
+	}
+	return checkResourceList(resources, b.Manifest, b.options)
+}
+
+func (b *ChartBundle) loadI18n() error {
+	entries, err := fs.ReadDir(b.FS, "i18n")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b.I18n = make(map[string][]byte)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(b.FS, path.Join("i18n", e.Name(), ManifestName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		b.I18n[e.Name()] = data
+	}
+	return nil
+}
+
+// Locales returns the locales with an i18n overlay, sorted.
+func (b *ChartBundle) Locales() []string {
+	locales := make([]string, 0, len(b.I18n))
+	for l := range b.I18n {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+func (b *ChartBundle) loadOwners() error {
+	owners, err := LoadOwners(b.FS, ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b.Owners = owners
+	return nil
+}
+
+var utf8bom = []byte{0xEF, 0xBB, 0xBF}
+
+// loadChartFS loads the chart at the root of fsys like Helm loads a chart
+// folder, honouring .helmignore. Symbolic links to files are followed, links
+// to folders are not.
+func loadChartFS(fsys fs.FS) (*chart.Chart, error) {
+	data, err := fs.ReadFile(fsys, helmIgnoreFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	rules, err := parseHelmIgnore(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*helmLoader.BufferedFile
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		fi, err := fs.Stat(fsys, name)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rules.ignore(name, true) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if rules.ignore(name, fi.IsDir()) || fi.IsDir() {
+			return nil
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("cannot load irregular file %s as it has file mode type bits set", name)
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", name, err)
+		}
+		files = append(files, &helmLoader.BufferedFile{Name: name, Data: bytes.TrimPrefix(data, utf8bom)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return helmLoader.LoadFiles(files)
+}
+
+// fsFileExists reports whether name is a file of fsys.
+func fsFileExists(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && !info.IsDir()
+}
+
+// fsDirExists reports whether name is a folder of fsys.
+func fsDirExists(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,279 @@
+package oachecker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the version of oachecker. It is part of every cache key, so a
+// new release never reuses reports of an older one.
+const Version = "0.2.0"
+
+// DefaultCacheSize is the size bound of a Cache created with maxBytes 0.
+const DefaultCacheSize = 64 << 20
+
+const cacheExt = ".json"
+
+// Cache stores lint reports on disk keyed by the content of the chart, the
+// effective lint options and Version, so linting an unchanged chart again
+// skips the Helm dry run. Least recently used reports are evicted once the
+// cache grows past MaxBytes.
+type Cache struct {
+	Dir      string
+	MaxBytes int64
+
+	mu sync.Mutex
+}
+
+// NewCache returns a cache storing reports in dir, creating it if needed.
+func NewCache(dir string, maxBytes int64) (*Cache, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir, MaxBytes: maxBytes}, nil
+}
+
+// DefaultCacheDir is the oachecker folder in the user cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "oachecker"), nil
+}
+
+// cacheableOptions are the fields of LintOptions changing the outcome of a
+// lint run.
+type cacheableOptions struct {
+	Owner                string
+	Admin                string
+	SkipManifestCheck    bool
+	SkipResourceCheck    bool
+	SkipFolderCheck      bool
+	SkipSameVersionCheck bool
+	CollectAll           bool
+	AppDataCheck         bool
+	RuleEnabled          map[string]bool
+	RuleSeverity         map[string]Severity
+	ReservedWords        []string
+	Categories           []string
+	Values               map[string]interface{}
+	Baseline             *Baseline
+}
+
+// Key returns the cache key of linting oacPath with options. Options with
+// custom validators can not be cached, as their behaviour is unknown; ok is
+// false for them.
+func (c *Cache) Key(oacPath string, options *LintOptions) (key string, ok bool, err error) {
+	return c.key(newChartBundle(oacPath, nil), options)
+}
+
+// KeyFS is Key for the chart folder dir of fsys.
+func (c *Cache) KeyFS(fsys fs.FS, dir string, options *LintOptions) (key string, ok bool, err error) {
+	b, err := newChartBundleFS(fsys, dir, nil)
+	if err != nil {
+		return "", false, err
+	}
+	return c.key(b, options)
+}
+
+func (c *Cache) key(b *ChartBundle, options *LintOptions) (key string, ok bool, err error) {
+	if options == nil {
+		options = DefaultLintOptions()
+	}
+	if len(options.CustomValidators) > 0 || len(options.ContextValidators) > 0 {
+		return "", false, nil
+	}
+	h := sha256.New()
+	h.Write([]byte(Version))
+	h.Write([]byte{0})
+	opts, err := json.Marshal(cacheableOptions{
+		Owner:                options.Owner,
+		Admin:                options.Admin,
+		SkipManifestCheck:    options.SkipManifestCheck,
+		SkipResourceCheck:    options.SkipResourceCheck,
+		SkipFolderCheck:      options.SkipFolderCheck,
+		SkipSameVersionCheck: options.SkipSameVersionCheck,
+		CollectAll:           options.CollectAll,
+		AppDataCheck:         options.AppDataCheck,
+		RuleEnabled:          options.RuleEnabled,
+		RuleSeverity:         options.RuleSeverity,
+		ReservedWords:        options.ReservedWords,
+		Categories:           options.Categories,
+		Values:               options.Values,
+		Baseline:             options.Baseline,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	h.Write(opts)
+	h.Write([]byte{0})
+	// the folder name is checked too, so it is part of the content
+	h.Write([]byte(b.Folder))
+	h.Write([]byte{0})
+	if err := hashFS(h, b.FS); err != nil {
+		return "", false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// hashFS writes the path and content of every file of fsys to w, in lexical
+// order. Like loadChartFS it follows symbolic links to files, so a changed
+// link target changes the hash.
+func hashFS(w io.Writer, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			_, err = io.WriteString(w, "d "+name+"\x00")
+			return err
+		}
+		fi, err := fs.Stat(fsys, name)
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			_, err = io.WriteString(w, "i "+name+" "+fi.Mode().String()+"\x00")
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "f "+name+"\x00"); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		_, err = w.Write([]byte{0})
+		return err
+	})
+}
+
+func (c *Cache) file(key string) string {
+	return filepath.Join(c.Dir, key+cacheExt)
+}
+
+// Get returns the report stored under key. Findings read back from the cache
+// keep their fields but not the wrapped error.
+func (c *Cache) Get(key string) (*Report, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := os.ReadFile(c.file(key))
+	if err != nil {
+		return nil, false
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		os.Remove(c.file(key))
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(c.file(key), now, now)
+	return &report, true
+}
+
+// Put stores report under key and evicts old reports past MaxBytes.
+func (c *Cache) Put(key string, report *Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tmp, err := os.CreateTemp(c.Dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.file(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes least recently used reports until the cache fits MaxBytes.
+func (c *Cache) evict() error {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+	var files []fs.FileInfo
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), cacheExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, f := range files {
+		if total <= c.MaxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.Dir, f.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= f.Size()
+	}
+	return nil
+}
+
+// Invalidate removes the report stored under key.
+func (c *Cache) Invalidate(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.file(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Clear removes every report of the cache.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), cacheExt) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.Dir, e.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
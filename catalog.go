@@ -0,0 +1,113 @@
+package oachecker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CatalogOptions configure LintCatalog.
+type CatalogOptions struct {
+	// Options are used for every chart. When nil, each chart gets the
+	// options of its .oachecker.yaml files, see LintOptionsForChart.
+	Options *LintOptions
+	// ChartOptions, when set, returns the options of the chart folder at
+	// oacPath instead of Options. An error is reported as a finding of the
+	// chart.
+	ChartOptions func(oacPath string) (*LintOptions, error)
+	// Workers bounds the charts linted at the same time, GOMAXPROCS when 0.
+	Workers int
+}
+
+// FindCharts returns the chart folders directly under root, sorted by name.
+// A folder is a chart when it holds a Chart.yaml or an OlaresManifest.yaml;
+// hidden folders are ignored.
+func FindCharts(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var charts []string
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		if fileExists(filepath.Join(dir, "Chart.yaml")) || fileExists(filepath.Join(dir, ManifestName)) {
+			charts = append(charts, dir)
+		}
+	}
+	sort.Strings(charts)
+	return charts, nil
+}
+
+// LintCatalog lints every chart folder of an app store repository, see
+// FindCharts, and returns one report per chart in the order of FindCharts.
+func LintCatalog(root string, options *CatalogOptions) ([]*Report, error) {
+	return LintCatalogContext(context.Background(), root, options)
+}
+
+// LintCatalogContext is LintCatalog bounded by ctx. Charts not linted when
+// ctx is done get a report with an OAC-RUN-001 finding.
+func LintCatalogContext(ctx context.Context, root string, options *CatalogOptions) ([]*Report, error) {
+	if options == nil {
+		options = &CatalogOptions{}
+	}
+	charts, err := FindCharts(root)
+	if err != nil {
+		return nil, err
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	reports := make([]*Report, len(charts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(charts); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				reports[i] = lintCatalogChart(ctx, charts[i], options)
+			}
+		}()
+	}
+	for i := range charts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return reports, nil
+}
+
+// lintCatalogChart lints one chart of a catalog. A panic in a check is
+// reported as a finding of the chart instead of ending the whole run.
+func lintCatalogChart(ctx context.Context, oacPath string, catalog *CatalogOptions) (report *Report) {
+	defer func() {
+		if r := recover(); r != nil {
+			report = &Report{Chart: filepath.Base(oacPath), Path: oacPath}
+			report.add(findingf(RuleLintPanic, "", "lint panicked: %v\n%s", r, debug.Stack()))
+		}
+	}()
+	options, chartOptions := catalog.Options, catalog.ChartOptions
+	if options == nil && chartOptions == nil {
+		chartOptions = LintOptionsForChart
+	}
+	if chartOptions != nil {
+		var err error
+		if options, err = chartOptions(oacPath); err != nil {
+			report = &Report{Chart: filepath.Base(oacPath), Path: oacPath}
+			report.add(newFinding(RuleConfigLoad, ConfigFileName, fmt.Errorf("failed to load configuration: %w", err)))
+			return report
+		}
+	}
+	return LintReportContext(ctx, oacPath, options)
+}
@@ -5,22 +5,19 @@ var (
 		"Data", "Entertainment", "Productivity", "Lifestyle", "Developer", "Multimedia"}
 )
 
-func checkCategories(categories []string) bool {
+// checkCategories reports whether categories is non-empty and only uses
+// allowed categories, validCategoriesSlice when allowed is empty.
+func checkCategories(categories []string, allowed ...string) bool {
 	if len(categories) == 0 {
 		return false
 	}
+	if len(allowed) == 0 {
+		allowed = validCategoriesSlice
+	}
 
-	validCategories := map[string]bool{
-		"AI":             true,
-		"Blockchain":     true,
-		"Utilities":      true,
-		"Social Network": true,
-		"Data":           true,
-		"Entertainment":  true,
-		"Productivity":   true,
-		"Lifestyle":      true,
-		"Developer":      true,
-		"Multimedia":     true,
+	validCategories := make(map[string]bool, len(allowed))
+	for _, category := range allowed {
+		validCategories[category] = true
 	}
 
 	for _, category := range categories {
@@ -1,6 +1,10 @@
 package oachecker
 
-import vd "github.com/bytedance/go-tagexpr/v2/validator"
+import (
+	"context"
+	"io/fs"
+	"os"
+)
 
 type LintOptions struct {
 	Owner             string
@@ -10,7 +14,33 @@ type LintOptions struct {
 
 	SkipFolderCheck      bool
 	SkipSameVersionCheck bool
-	CustomValidators     []func(string, *AppConfiguration) error
+	// CollectAll runs every stage even when an earlier one failed, so all
+	// problems of a chart are reported at once.
+	CollectAll       bool
+	CustomValidators []func(string, *AppConfiguration) error
+	// ContextValidators are custom validators receiving the context of the
+	// lint run, so they can stop early when it is canceled.
+	ContextValidators []func(context.Context, string, *AppConfiguration) error
+	// AppDataCheck runs the appData permission check on the templates of
+	// the chart as read by the lint run, set by WithAppDataValidator.
+	AppDataCheck bool
+	// RuleEnabled overrides per rule id whether a rule is reported.
+	RuleEnabled map[string]bool
+	// RuleSeverity overrides per rule id the severity findings are reported with.
+	RuleSeverity map[string]Severity
+	// ReservedWords are folder names rejected in addition to the built-in list.
+	ReservedWords []string
+	// Categories replaces the list of allowed metadata.categories when set.
+	Categories []string
+	// Values are merged over the fake values used to render the chart.
+	Values map[string]interface{}
+	// Baseline hides the findings recorded in it, so only new problems fail.
+	Baseline *Baseline
+	// Cache reuses the report of an earlier run on the same chart content
+	// with the same options. Runs with custom validators are not cached.
+	// Only the report entry points use it: cached findings lose their
+	// wrapped errors, so Lint, LintContext and LintFS always run.
+	Cache *Cache
 }
 
 func DefaultLintOptions() *LintOptions {
@@ -21,7 +51,10 @@ func DefaultLintOptions() *LintOptions {
 		SkipResourceCheck:    false,
 		SkipFolderCheck:      false,
 		SkipSameVersionCheck: true,
+		CollectAll:           false,
 		CustomValidators:     []func(string, *AppConfiguration) error{},
+		RuleEnabled:          map[string]bool{},
+		RuleSeverity:         map[string]Severity{},
 	}
 }
 
@@ -46,8 +79,13 @@ func (o *LintOptions) WithCustomValidator(validator func(string, *AppConfigurati
 	return o
 }
 
+func (o *LintOptions) WithContextValidator(validator func(context.Context, string, *AppConfiguration) error) *LintOptions {
+	o.ContextValidators = append(o.ContextValidators, validator)
+	return o
+}
+
 func (o *LintOptions) WithAppDataValidator() {
-	o.CustomValidators = append(o.CustomValidators, CheckAppData)
+	o.AppDataCheck = true
 }
 
 func (o *LintOptions) SkipManifest() *LintOptions {
@@ -65,134 +103,230 @@ func (o *LintOptions) SkipSameVersion() *LintOptions {
 	return o
 }
 
-func CheckChart(oacPath string) (err error) {
-	err = CheckChartFolder(oacPath)
-	if err != nil {
-		return err
+func (o *LintOptions) EnableRule(ids ...string) *LintOptions {
+	return o.setRules(true, ids)
+}
+
+func (o *LintOptions) DisableRule(ids ...string) *LintOptions {
+	return o.setRules(false, ids)
+}
+
+func (o *LintOptions) setRules(enabled bool, ids []string) *LintOptions {
+	if o.RuleEnabled == nil {
+		o.RuleEnabled = make(map[string]bool)
 	}
-	err = CheckAppCfg(oacPath)
-	if err != nil {
-		return err
+	for _, id := range ids {
+		o.RuleEnabled[id] = enabled
 	}
-	err = CheckServiceAccountRole(oacPath)
-	if err != nil {
-		return err
-	}
-	return nil
+	return o
 }
 
-func CheckManifest(oacPath string, cfg *AppConfiguration) error {
-	err := vd.Validate(cfg, true)
-	if err != nil {
-		return err
-	}
-	err = CheckSupportedArch(cfg)
-	if err != nil {
-		return err
+func (o *LintOptions) WithRuleSeverity(id string, severity Severity) *LintOptions {
+	if o.RuleSeverity == nil {
+		o.RuleSeverity = make(map[string]Severity)
 	}
+	o.RuleSeverity[id] = severity
+	return o
+}
 
-	err = CheckAppEntrances(cfg)
+func (o *LintOptions) WithBaseline(baseline *Baseline) *LintOptions {
+	o.Baseline = baseline
+	return o
+}
+
+func (o *LintOptions) WithCache(cache *Cache) *LintOptions {
+	o.Cache = cache
+	return o
+}
+
+func (o *LintOptions) WithCollectAll() *LintOptions {
+	o.CollectAll = true
+	return o
+}
+
+func CheckChart(oacPath string) (err error) {
+	return CheckChartContext(context.Background(), oacPath)
+}
+
+// CheckChartContext is CheckChart, stopping with the error of ctx once it is
+// done.
+func CheckChartContext(ctx context.Context, oacPath string) (err error) {
+	return checkChartBundle(ctx, newChartBundle(oacPath, nil))
+}
+
+// CheckChartFS is CheckChartContext for the chart folder dir of fsys.
+func CheckChartFS(ctx context.Context, fsys fs.FS, dir string) error {
+	b, err := newChartBundleFS(fsys, dir, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	return checkChartBundle(ctx, b)
 }
 
-func CheckManifestFromFile(oacPath string, opts ...func(map[string]interface{})) error {
-	cfg, err := GetAppConfiguration(oacPath, opts...)
-	if err != nil {
-		return err
+// checkChartBundle runs the checks of CheckChart on one bundle, so the chart
+// is parsed and rendered once.
+func checkChartBundle(ctx context.Context, b *ChartBundle) error {
+	_, _, _, err := bundleFolderCheck(b)
+	if findings := asFindings(err, RuleChartYaml, ""); len(findings) > 0 {
+		return findingsErr(findings)
 	}
-	err = vd.Validate(cfg, true)
-	if err != nil {
-		return err
+	if findings := checkAppCfg(ctx, b, true); len(findings) > 0 {
+		return findingsErr(findings)
 	}
-	err = CheckSupportedArch(cfg)
-	if err != nil {
-		return err
+	findings := b.serviceAccountFindings(ctx)
+	if len(findings) > 0 {
+		return findingsErr(findings)
 	}
+	return nil
+}
+
+func CheckManifest(oacPath string, cfg *AppConfiguration) error {
+	return findingsErr(checkManifest(cfg))
+}
+
+func checkManifest(cfg *AppConfiguration) []*Finding {
+	findings := validateManifest(cfg, true)
+	findings = append(findings, checkSupportedArch(cfg)...)
+	return append(findings, checkAppEntrances(cfg)...)
+}
+
+func CheckManifestFromFile(oacPath string, opts ...func(map[string]interface{})) error {
+	return CheckManifestFS(os.DirFS(oacPath), opts...)
+}
 
-	err = CheckAppEntrances(cfg)
+// CheckManifestFS is CheckManifestFromFile for a chart folder at the root of
+// fsys.
+func CheckManifestFS(fsys fs.FS, opts ...func(map[string]interface{})) error {
+	content, err := fs.ReadFile(fsys, ManifestName)
 	if err != nil {
-		return err
+		return newFinding(RuleManifestLoad, ManifestName, err)
 	}
-	return nil
+	return CheckManifestFromContent(content, opts...)
 }
 
 func CheckManifestFromContent(content []byte, opts ...func(map[string]interface{})) error {
-	cfg, err := GetAppConfigurationFromContent(content, opts...)
-	if err != nil {
-		return err
-	}
-	err = vd.Validate(cfg, true)
+	cfg, locator, err := loadManifest(content, opts...)
 	if err != nil {
-		return err
-	}
-	err = CheckSupportedArch(cfg)
-	if err != nil {
-		return err
+		return newFinding(RuleManifestLoad, ManifestName, err)
 	}
+	findings := checkManifest(cfg)
+	locator.annotate(findings)
+	return findingsErr(findings)
+}
 
-	err = CheckAppEntrances(cfg)
+// LintManifestReport checks OlaresManifest.yaml content on its own, like
+// CheckManifestFromContent, rendered with the owner and admin of options. The
+// findings are reported under chart with the rules of options applied and
+// filtered by the suppression comments of content and the baseline of
+// options.
+func LintManifestReport(chart string, content []byte, options *LintOptions) *Report {
+	if options == nil {
+		options = DefaultLintOptions()
+	}
+	report := &Report{Chart: chart, Findings: []*Finding{}}
+	suppressions := ParseSuppressions(ManifestName, content)
+	baseline := options.Baseline.matcher(chart)
+	stages := map[string]bool{StageLoad: true, StageSuppression: true}
+	cfg, locator, err := loadManifest(content, manifestOptions(options)...)
 	if err != nil {
-		return err
+		report.filter(options.applyRules([]*Finding{newFinding(RuleManifestLoad, ManifestName, err)}), suppressions, baseline)
+	} else {
+		stages[StageManifest] = true
+		findings := options.applyRules(checkManifest(cfg))
+		locator.annotate(findings)
+		report.filter(findings, suppressions, baseline)
 	}
-	return nil
+	report.filter(checkSuppressions(suppressions, stages, options), nil, baseline)
+	report.Rules = options.checkedRules(stages)
+	return report
 }
 
 func Lint(oacPath string, options *LintOptions) error {
+	return LintContext(context.Background(), oacPath, options)
+}
+
+// LintContext is Lint bounded by ctx. Once ctx is done the running stage is
+// abandoned and the returned error matches ctx.Err() with errors.Is.
+func LintContext(ctx context.Context, oacPath string, options *LintOptions) error {
 	if options == nil {
 		options = DefaultLintOptions()
 	}
+	return lintErr(lintReport(ctx, newChartBundle(oacPath, options), options, false), options)
+}
 
-	var opts []func(map[string]interface{})
-	if options.Owner != "" {
-		opts = append(opts, WithOwner(options.Owner))
-	}
-	if options.Admin != "" {
-		opts = append(opts, WithAdmin(options.Admin))
-	}
+// LintFS is LintContext for the chart folder dir of fsys, e.g. a chart held
+// in memory. Custom validators are passed dir as the chart path.
+func LintFS(ctx context.Context, fsys fs.FS, dir string, options *LintOptions) error {
+	return lintErr(lintReportFS(ctx, fsys, dir, options, false), options)
+}
 
-	cfg, err := GetAppConfiguration(oacPath, opts...)
-	if err != nil {
-		return err
+func lintErr(report *Report, options *LintOptions) error {
+	err := report.Err()
+	if m, ok := err.(*MultiError); ok && options != nil && options.CollectAll {
+		// findings of every stage, ordered by location rather than stage
+		m.Sort()
 	}
+	return err
+}
 
-	if !options.SkipManifestCheck {
-		err = CheckManifest(oacPath, cfg)
-		if err != nil {
-			return err
-		}
-	}
+// LintReport runs the same checks as Lint and returns the findings as a
+// Report instead of a flattened error. Unless options.CollectAll is set it
+// stops after the first stage reporting errors.
+func LintReport(oacPath string, options *LintOptions) *Report {
+	return LintReportContext(context.Background(), oacPath, options)
+}
 
-	for _, validator := range options.CustomValidators {
-		if err := validator(oacPath, cfg); err != nil {
-			return err
-		}
+// LintReportContext is LintReport bounded by ctx. A canceled run reports an
+// OAC-RUN-001 finding and skips the stages that did not get to run.
+func LintReportContext(ctx context.Context, oacPath string, options *LintOptions) *Report {
+	if options == nil {
+		options = DefaultLintOptions()
 	}
+	return lintReport(ctx, newChartBundle(oacPath, options), options, true)
+}
 
-	if !options.SkipResourceCheck {
-		err = CheckResource(oacPath, cfg, options)
-		if err != nil {
-			return err
-		}
-	}
+// LintReportFS is LintReportContext for the chart folder dir of fsys.
+// Custom validators are passed dir as the chart path.
+func LintReportFS(ctx context.Context, fsys fs.FS, dir string, options *LintOptions) *Report {
+	return lintReportFS(ctx, fsys, dir, options, true)
+}
 
-	if !options.SkipFolderCheck {
-		err = CheckChartFolder(oacPath)
-		if err != nil {
-			return err
-		}
+func lintReportFS(ctx context.Context, fsys fs.FS, dir string, options *LintOptions, cached bool) *Report {
+	if options == nil {
+		options = DefaultLintOptions()
 	}
+	b, err := newChartBundleFS(fsys, dir, options)
+	if err != nil {
+		report := &Report{Chart: b.Folder, Path: dir}
+		report.add(newFinding(RuleFolderExists, "", err))
+		return report
+	}
+	return lintReport(ctx, b, options, cached)
+}
 
-	if !options.SkipSameVersionCheck {
-		err = CheckSameVersion(oacPath)
-		if err != nil {
-			return err
+// lintReport lints b, reusing and filling options.Cache when cached is set.
+func lintReport(ctx context.Context, b *ChartBundle, options *LintOptions, cached bool) *Report {
+	var key string
+	if cached && options.Cache != nil {
+		var ok bool
+		var err error
+		if key, ok, err = options.Cache.key(b, options); err == nil && ok {
+			if report, hit := options.Cache.Get(key); hit {
+				report.Path = b.Path
+				return report
+			}
+		} else {
+			key = ""
 		}
 	}
-
-	return nil
+	l := &linter{ctx: ctx, path: b.Path, options: options, bundle: b}
+	report := &Report{Chart: b.Folder, Path: b.Path}
+	l.run(report)
+	if key != "" && ctx.Err() == nil {
+		// a failing cache only costs the next run its shortcut
+		_ = options.Cache.Put(key, report)
+	}
+	return report
 }
 
 func LintWithDefaultOptions(oacPath string) error {
@@ -1,9 +1,25 @@
 package oachecker
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 // TestCheckChart tests the CheckChart function
@@ -162,8 +178,8 @@ func TestLintOptions(t *testing.T) {
 	// Test WithAppDataValidator
 	options = DefaultLintOptions()
 	options.WithAppDataValidator()
-	if len(options.CustomValidators) != 1 {
-		t.Errorf("WithAppDataValidator failed, expected 1 validator, got %d", len(options.CustomValidators))
+	if !options.AppDataCheck {
+		t.Error("WithAppDataValidator failed, AppDataCheck should be true")
 	}
 
 	// Test SkipManifest
@@ -179,6 +195,1707 @@ func TestLintOptions(t *testing.T) {
 	}
 }
 
+// TestLintReport tests the LintReport function
+func TestLintReport(t *testing.T) {
+	// Test with valid chart path
+	report := LintReport("testdata/firefox", DefaultLintOptions().SkipResources())
+	if report.Chart != "firefox" || len(report.Findings) != 0 {
+		t.Errorf("LintReport returned unexpected report for valid chart: %+v", report)
+	}
+
+	// Test with an unsupported arch
+	chartPath := copyTestChart(t, func(manifest string) string {
+		return strings.Replace(manifest, "  - arm64\n", "  - sparc\n", 1)
+	})
+	report = LintReport(chartPath, DefaultLintOptions().SkipResources())
+	if len(report.Findings) != 1 {
+		t.Fatalf("LintReport expected 1 finding, got %d", len(report.Findings))
+	}
+	f := report.Findings[0]
+	if f.RuleID != RuleSupportArch || f.Severity != SeverityError || f.Chart != "firefox" ||
+		f.File != ManifestName || f.Path != "Spec.SupportArch[1]" {
+		t.Errorf("LintReport returned unexpected finding: %+v", f)
+	}
+
+	// Test that Lint returns the same finding as an error
+	var lf *Finding
+	if err := Lint(chartPath, DefaultLintOptions().SkipResources()); !errors.As(err, &lf) || lf.RuleID != RuleSupportArch {
+		t.Errorf("Lint should return a finding error, got: %v", err)
+	}
+
+	// Test with invalid chart path
+	report = LintReport("testdata/nonexistent", DefaultLintOptions())
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != RuleManifestLoad {
+		t.Errorf("LintReport expected a manifest load finding, got: %+v", report.Findings)
+	}
+}
+
+// TestLintCollectAll tests the collect-all lint mode
+func TestLintCollectAll(t *testing.T) {
+	chartPath := copyTestChart(t, func(manifest string) string {
+		return strings.Replace(manifest, "  - arm64\n", "  - sparc\n", 1)
+	})
+	if err := os.Remove(filepath.Join(chartPath, "values.yaml")); err != nil {
+		t.Fatalf("Failed to remove values.yaml: %v", err)
+	}
+
+	// Test that fail-fast mode stops at the manifest stage
+	report := LintReport(chartPath, DefaultLintOptions().SkipResources())
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != RuleSupportArch {
+		t.Errorf("LintReport expected only the arch finding, got: %+v", report.Findings)
+	}
+
+	// Test that collect-all mode reports the folder problem as well
+	report = LintReport(chartPath, DefaultLintOptions().SkipResources().WithCollectAll())
+	rules := make(map[string]bool)
+	for _, f := range report.Findings {
+		rules[f.RuleID] = true
+	}
+	if !rules[RuleSupportArch] || !rules[RuleValuesYaml] {
+		t.Errorf("LintReport with collect-all returned unexpected findings: %+v", report.Findings)
+	}
+
+	// Test that stages needing the manifest are skipped when it does not parse
+	chartPath = copyTestChart(t, func(manifest string) string {
+		return manifest + "\n{{- if }}"
+	})
+	report = LintReport(chartPath, DefaultLintOptions().WithCollectAll())
+	skipped := make(map[string]bool)
+	for _, s := range report.Skipped {
+		skipped[s.Stage] = true
+	}
+	if !skipped[StageManifest] || !skipped[StageCustom] || !skipped[StageResource] || skipped[StageFolder] {
+		t.Errorf("LintReport with collect-all skipped unexpected stages: %+v", report.Skipped)
+	}
+	if len(report.Findings) == 0 || report.Findings[0].RuleID != RuleManifestLoad {
+		t.Errorf("LintReport expected a manifest load finding first, got: %+v", report.Findings)
+	}
+}
+
+// TestRuleOptions tests enabling, disabling and re-severity of rules
+func TestRuleOptions(t *testing.T) {
+	chartPath := copyTestChart(t, func(manifest string) string {
+		return strings.Replace(manifest, "  - arm64\n", "  - sparc\n", 1)
+	})
+
+	// Test that a disabled rule is not reported
+	options := DefaultLintOptions().SkipResources().DisableRule(RuleSupportArch)
+	if report := LintReport(chartPath, options); len(report.Findings) != 0 {
+		t.Errorf("LintReport reported a disabled rule: %+v", report.Findings)
+	}
+
+	// Test that a rule downgraded to warning does not fail the lint
+	options = DefaultLintOptions().SkipResources().WithRuleSeverity(RuleSupportArch, SeverityWarning)
+	report := LintReport(chartPath, options)
+	if len(report.Findings) != 1 || report.Findings[0].Severity != SeverityWarning {
+		t.Errorf("LintReport expected one warning, got: %+v", report.Findings)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("Report.Err should ignore warnings, got: %v", err)
+	}
+
+	// Test enabling a rule that is disabled by default
+	options = DefaultLintOptions()
+	if options.ruleEnabled(RuleResourceNamespace) {
+		t.Error("resource namespace rule should be disabled by default")
+	}
+	if !options.EnableRule(RuleResourceNamespace).ruleEnabled(RuleResourceNamespace) {
+		t.Error("EnableRule failed to enable the resource namespace rule")
+	}
+}
+
+// TestRules tests the rule registry
+func TestRules(t *testing.T) {
+	rules := Rules()
+	seen := make(map[string]bool)
+	for i, r := range rules {
+		if seen[r.ID] {
+			t.Errorf("rule %s registered twice", r.ID)
+		}
+		seen[r.ID] = true
+		if i > 0 && rules[i-1].ID > r.ID {
+			t.Errorf("Rules not sorted at %s", r.ID)
+		}
+		if r.Name == "" || r.Stage == "" || r.Description == "" {
+			t.Errorf("rule %s is missing metadata", r.ID)
+		}
+	}
+	if r, ok := LookupRule(RuleContainerResources); !ok || r.ID != "OAC-RES-003" {
+		t.Errorf("LookupRule returned unexpected rule: %+v", r)
+	}
+}
+
+// TestLintDefaultRules tests that the default Lint runs the checks it ran
+// before the rule registry: the RBAC, category and reserved name rules only
+// run on request
+func TestLintDefaultRules(t *testing.T) {
+	chart := mapChart(t, "testdata/firefox", "kube")
+	manifest := chart["kube/"+ManifestName]
+	manifest.Data = []byte(strings.Replace(string(manifest.Data), "  - Utilities\n", "  - Nonsense\n", 1))
+	chart["kube/templates/rbac.yaml"] = &fstest.MapFile{Data: []byte(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: kube-sa
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: kube-nodes
+rules:
+- apiGroups: ['*']
+  resources: [nodes]
+  verbs: [create]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: kube-nodes
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: kube-nodes
+subjects:
+- kind: ServiceAccount
+  name: kube-sa
+`)}
+	ruleIDs := func(report *Report) string {
+		var ids []string
+		for _, f := range report.Findings {
+			ids = append(ids, f.RuleID)
+		}
+		sort.Strings(ids)
+		return fmt.Sprint(ids)
+	}
+
+	want := ruleIDs(LintReport("testdata/firefox", DefaultLintOptions().WithCollectAll()))
+	if got := ruleIDs(LintReportFS(context.Background(), chart, "kube", DefaultLintOptions().WithCollectAll())); got != want {
+		t.Errorf("Expected the findings of the unchanged chart %s, got %s", want, got)
+	}
+	if err := LintFS(context.Background(), chart, "kube", DefaultLintOptions()); err == nil ||
+		errors.Is(err, ErrInvalidCategories) || errors.Is(err, ErrReservedFolderName) {
+		t.Errorf("Expected Lint to fail as on the unchanged chart only, got %v", err)
+	}
+
+	options := DefaultLintOptions().WithCollectAll().EnableRule(RuleServiceAccountRole, RuleCategories, RuleReservedFolderName)
+	report := LintReportFS(context.Background(), chart, "kube", options)
+	for _, id := range []string{RuleServiceAccountRole, RuleCategories, RuleReservedFolderName} {
+		if !hasRule(report.Findings, id) {
+			t.Errorf("Expected a %s finding once enabled, got %+v", id, report.Findings)
+		}
+	}
+}
+
+// TestLintOptionsForChart tests loading .oachecker.yaml files
+func TestLintOptionsForChart(t *testing.T) {
+	repo := t.TempDir()
+	chartPath := filepath.Join(repo, "charts", "firefox")
+	for _, dir := range []string{filepath.Join(repo, ".git"), chartPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := copyDir("testdata/firefox", chartPath); err != nil {
+		t.Fatalf("Failed to copy test chart: %v", err)
+	}
+	rootConfig := `owner: alice
+rules:
+  disable: [OAC-MAN-003, OAC-RES-007]
+categories: [Games]
+reservedWords: [browser]
+values:
+  bfl:
+    username: alice
+`
+	chartConfig := `admin: alice
+rules:
+  enable: [OAC-MAN-003, OAC-FLD-010]
+severity:
+  OAC-FLD-010: warning
+values:
+  bfl:
+    nodeName: node
+`
+	if err := os.WriteFile(filepath.Join(repo, ConfigFileName), []byte(rootConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(chartPath, ConfigFileName), []byte(chartConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	files, err := FindConfigFiles(chartPath)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("FindConfigFiles expected 2 files, got %v, %v", files, err)
+	}
+
+	options, err := LintOptionsForChart(chartPath)
+	if err != nil {
+		t.Fatalf("LintOptionsForChart failed: %v", err)
+	}
+	if options.Owner != "alice" || options.Admin != "alice" {
+		t.Errorf("unexpected owner/admin: %s/%s", options.Owner, options.Admin)
+	}
+	if !options.ruleEnabled(RuleSupportArch) || options.ruleEnabled(RuleResourceNamespace) {
+		t.Errorf("unexpected rule overrides: %v", options.RuleEnabled)
+	}
+	bfl, _ := options.Values["bfl"].(map[string]interface{})
+	if bfl["username"] != "alice" || bfl["nodeName"] != "node" {
+		t.Errorf("values were not merged: %v", options.Values)
+	}
+
+	report := LintReport(chartPath, options.SkipResources())
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != RuleCategories || report.Findings[0].Severity != SeverityWarning {
+		t.Errorf("LintReport expected a category warning, got: %+v", report.Findings)
+	}
+
+	// Test that unknown rules and severities are rejected
+	for _, config := range []string{
+		"rules:\n  disable: [OAC-RES-03]\n",
+		"rules:\n  enable: [OAC-MAN-999]\n",
+		"severity:\n  OAC-RES-03: warning\n",
+		"severity:\n  OAC-FLD-010: fatal\n",
+	} {
+		if err := os.WriteFile(filepath.Join(chartPath, ConfigFileName), []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LintOptionsForChart(chartPath); err == nil {
+			t.Errorf("LintOptionsForChart accepted config %q", config)
+		}
+	}
+}
+
+// TestSuppressions tests inline suppression comments
+func TestSuppressions(t *testing.T) {
+	chartPath := copyTestChart(t, func(manifest string) string {
+		manifest = strings.Replace(manifest, "  - arm64\n", "  - sparc\n", 1)
+		manifest = strings.Replace(manifest, "entrances:\n", "# oachecker:ignore OAC-MAN-004\nentrances:\n", 1)
+		return "# oachecker:ignore-file OAC-MAN-003 reason=legacy arch list\n" + manifest
+	})
+	report := LintReport(chartPath, DefaultLintOptions().SkipResources())
+	if len(report.Suppressed) != 1 || report.Suppressed[0].RuleID != RuleSupportArch {
+		t.Errorf("expected the arch finding to be suppressed, got: %+v", report.Suppressed)
+	}
+	rules := make(map[string]bool)
+	for _, f := range report.Findings {
+		rules[f.RuleID] = true
+		if f.Severity != SeverityWarning || f.File != ManifestName || f.Line == 0 {
+			t.Errorf("unexpected suppression finding: %+v", f)
+		}
+	}
+	if len(report.Findings) != 2 || !rules[RuleSuppressionUnused] || !rules[RuleSuppressionReason] {
+		t.Errorf("expected unused and reason-less suppression findings, got: %+v", report.Findings)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("suppressed report should not fail: %v", err)
+	}
+
+	// Test that a node suppression covers the node below it only
+	chartPath = copyTestChart(t, func(manifest string) string {
+		manifest = strings.Replace(manifest, "  - arm64\n", "  - sparc\n", 1)
+		return strings.Replace(manifest, "  supportArch:\n", "  # oachecker:ignore OAC-MAN-003 reason=test\n  supportArch:\n", 1)
+	})
+	report = LintReport(chartPath, DefaultLintOptions().SkipResources())
+	if len(report.Suppressed) != 1 || report.Suppressed[0].RuleID != RuleSupportArch || len(report.Findings) != 0 {
+		t.Errorf("expected the arch finding to be suppressed by the node, got: %+v, %+v", report.Findings, report.Suppressed)
+	}
+	chartPath = copyTestChart(t, func(manifest string) string {
+		manifest = strings.Replace(manifest, "  - arm64\n", "  - sparc\n", 1)
+		return strings.Replace(manifest, "  doc:", "  # oachecker:ignore OAC-MAN-003 reason=test\n  doc:", 1)
+	})
+	report = LintReport(chartPath, DefaultLintOptions().SkipResources())
+	if len(report.Suppressed) != 0 || !hasRule(report.Findings, RuleSupportArch) || !hasRule(report.Findings, RuleSuppressionUnused) {
+		t.Errorf("suppression of another node matched the arch finding: %+v, %+v", report.Findings, report.Suppressed)
+	}
+
+	// Test that findings without a file are not suppressed by Chart.yaml
+	chartPath = copyTestChart(t, nil)
+	reserved := filepath.Join(filepath.Dir(chartPath), "system")
+	if err := os.Rename(chartPath, reserved); err != nil {
+		t.Fatalf("Failed to rename chart folder: %v", err)
+	}
+	chartFile := filepath.Join(reserved, "Chart.yaml")
+	data, _ := os.ReadFile(chartFile)
+	data = append([]byte("# oachecker:ignore-file OAC-FLD-011 reason=test\n"), data...)
+	if err := os.WriteFile(chartFile, data, 0644); err != nil {
+		t.Fatalf("Failed to write Chart.yaml: %v", err)
+	}
+	report = LintReport(reserved, DefaultLintOptions().SkipResources().EnableRule(RuleReservedFolderName))
+	if len(report.Suppressed) != 0 || !hasRule(report.Findings, RuleReservedFolderName) {
+		t.Errorf("Chart.yaml suppression matched the folder finding: %+v, %+v", report.Findings, report.Suppressed)
+	}
+}
+
+// TestBaseline tests recording, filtering and pruning a baseline
+func TestBaseline(t *testing.T) {
+	options := func() *LintOptions {
+		return DefaultLintOptions().SkipResources().WithCollectAll()
+	}
+	chartPath := copyTestChart(t, func(manifest string) string {
+		return strings.Replace(manifest, "  - arm64\n", "  - sparc\n", 1)
+	})
+	report := LintReport(chartPath, options())
+	if len(report.Findings) != 1 {
+		t.Fatalf("expected one finding, got: %+v", report.Findings)
+	}
+	baselineFile := filepath.Join(t.TempDir(), "baseline.json")
+	if err := WriteBaseline(baselineFile, report); err != nil {
+		t.Fatalf("WriteBaseline failed: %v", err)
+	}
+	baseline, err := LoadBaseline(baselineFile)
+	if err != nil || len(baseline.Entries) != 1 {
+		t.Fatalf("LoadBaseline returned %+v, %v", baseline, err)
+	}
+
+	// Test that only the new problem is reported, even with shifted lines
+	manifestPath := filepath.Join(chartPath, ManifestName)
+	data, _ := os.ReadFile(manifestPath)
+	manifest := "# shifted\n# lines\n" + strings.Replace(string(data), "  - amd64\n", "  - vax\n", 1)
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	report = LintReport(chartPath, options().WithBaseline(baseline))
+	if len(report.Findings) != 1 || !strings.Contains(report.Findings[0].Message, "vax") || report.Baselined != 1 {
+		t.Errorf("expected only the new finding, got: %+v (baselined %d)", report.Findings, report.Baselined)
+	}
+
+	// Test that findings hidden by the baseline are not pruned
+	if pruned := baseline.Prune(report); pruned != 0 || len(baseline.Entries) != 1 {
+		t.Errorf("Prune dropped %d entries of a report filtered by the baseline, left %+v", pruned, baseline.Entries)
+	}
+
+	// Test that fixed problems are pruned
+	manifest = strings.Replace(manifest, "  - sparc\n", "  - arm64\n", 1)
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	report = LintReport(chartPath, options().WithBaseline(baseline))
+	if pruned := baseline.Prune(report, &Report{Chart: "other"}); pruned != 1 || len(baseline.Entries) != 0 {
+		t.Errorf("Prune expected to drop one entry, dropped %d, left %+v", pruned, baseline.Entries)
+	}
+
+	// Test that reordering entries keeps the fingerprints
+	first := newFinding(RuleSupportArch, ManifestName, fmt.Errorf(UnsupportedArch, "sparc")).withPath("Spec.SupportArch[1]")
+	moved := newFinding(RuleSupportArch, ManifestName, fmt.Errorf(UnsupportedArch, "sparc")).withPath("Spec.SupportArch[3]")
+	if Fingerprint(first) != Fingerprint(moved) {
+		t.Error("Fingerprint changed with the index of the arch")
+	}
+	other := newFinding(RuleSupportArch, ManifestName, fmt.Errorf(UnsupportedArch, "vax"))
+	if Fingerprint(other) == Fingerprint(newFinding(RuleSupportArch, ManifestName, fmt.Errorf(UnsupportedArch, "sparc"))) {
+		t.Error("Fingerprint ignores the values of the message")
+	}
+	older := findingf(RulePrVersionIncrease, "Chart.yaml", "version 1.2.3 must be greater than 1.2.4")
+	if Fingerprint(older) == Fingerprint(findingf(RulePrVersionIncrease, "Chart.yaml", "version 1.2.5 must be greater than 1.2.6")) {
+		t.Error("Fingerprint ignores the numbers of the message")
+	}
+}
+
+// TestWriteSARIF tests the SARIF output of a report
+func TestWriteSARIF(t *testing.T) {
+	report := &Report{
+		Chart: "firefox",
+		Path:  "testdata/firefox",
+		Findings: []*Finding{
+			findingf(RuleSupportArch, ManifestName, "unsupport arch: sparc").at(34, 5),
+			findingf(RuleFolderName, "", InvalidFolderName, "Firefox"),
+		},
+		Suppressed: []*Finding{
+			findingf(RuleSuppressionReason, ManifestName, "suppressed"),
+		},
+	}
+	report.Findings[1].Severity = SeverityWarning
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, report); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+	var log SARIFLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("WriteSARIF wrote invalid json: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Tool.Driver.Rules) != len(Rules()) {
+		t.Fatalf("unexpected SARIF log: %+v", log)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	loc := results[0].Locations[0].PhysicalLocation
+	if results[0].Level != "error" || loc.ArtifactLocation.URI != "testdata/firefox/OlaresManifest.yaml" ||
+		loc.Region == nil || loc.Region.StartLine != 34 {
+		t.Errorf("unexpected first result: %+v", results[0])
+	}
+	if log.Runs[0].Tool.Driver.Rules[results[0].RuleIndex].ID != RuleSupportArch {
+		t.Errorf("rule index %d does not point at %s", results[0].RuleIndex, RuleSupportArch)
+	}
+	if results[1].Level != "warning" || results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI != "testdata/firefox" {
+		t.Errorf("unexpected second result: %+v", results[1])
+	}
+	if len(results[2].Suppressions) != 1 {
+		t.Errorf("suppressed finding should carry a suppression: %+v", results[2])
+	}
+}
+
+// TestReporters tests the built-in reporters fed by one MultiReporter
+func TestReporters(t *testing.T) {
+	reports := []*Report{
+		{
+			Chart: "firefox",
+			Path:  "testdata/firefox",
+			Findings: []*Finding{
+				findingf(RuleSupportArch, ManifestName, "unsupport arch: sparc").at(34, 5),
+				findingf(RuleSuppressionUnused, ManifestName, "suppression of OAC-MAN-004 does not match any finding, 100%%").at(40, 0),
+			},
+			Skipped: []SkippedStage{{Stage: StageResource, Reason: "stage load failed"}},
+		},
+		{Chart: "chrome", Path: "charts/chrome"},
+	}
+	var text, gh, junit, js bytes.Buffer
+	reporters := make([]Reporter, 0)
+	for format, w := range map[string]*bytes.Buffer{FormatText: &text, FormatGitHub: &gh, FormatJUnit: &junit, FormatJSON: &js} {
+		r, err := NewReporter(format, w)
+		if err != nil {
+			t.Fatalf("NewReporter(%s) failed: %v", format, err)
+		}
+		reporters = append(reporters, r)
+	}
+	if _, err := NewReporter("yaml", &text); err == nil {
+		t.Error("NewReporter should reject unknown formats")
+	}
+	if err := MultiReporter(reporters...).Report(reports); err != nil {
+		t.Fatalf("MultiReporter failed: %v", err)
+	}
+
+	wantText := "testdata/firefox/OlaresManifest.yaml:34:5: error: unsupport arch: sparc [OAC-MAN-003]\n"
+	if !strings.HasPrefix(text.String(), wantText) || !strings.HasSuffix(text.String(), "chrome: no problems found\n") {
+		t.Errorf("unexpected text output:\n%s", text.String())
+	}
+
+	wantGH := "::error file=testdata/firefox/OlaresManifest.yaml,line=34,col=5,title=OAC-MAN-003::unsupport arch: sparc\n" +
+		"::warning file=testdata/firefox/OlaresManifest.yaml,line=40,title=OAC-SUP-001::suppression of OAC-MAN-004 does not match any finding, 100%25\n"
+	if gh.String() != wantGH {
+		t.Errorf("unexpected github output:\n%s", gh.String())
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(junit.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid junit xml: %v", err)
+	}
+	if len(suites.Suites) != 2 || suites.Failures != 1 || suites.Suites[0].Skipped == 0 {
+		t.Errorf("unexpected junit suites: %+v", suites)
+	}
+
+	var decoded []*Report
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[0].Findings[0].RuleID != RuleSupportArch {
+		t.Errorf("unexpected json output: %v, %s", err, js.String())
+	}
+}
+
+// TestJUnitReporterRules tests that only the rules a lint run checked pass
+func TestJUnitReporterRules(t *testing.T) {
+	options := DefaultLintOptions().SkipResources().DisableRule(RuleSupportArch)
+	report := LintReport("testdata/firefox", options)
+	var junit bytes.Buffer
+	if err := NewJUnitReporter(&junit).Report([]*Report{report}); err != nil {
+		t.Fatalf("JUnit reporter failed: %v", err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(junit.Bytes(), &suites); err != nil || len(suites.Suites) != 1 {
+		t.Fatalf("invalid junit xml: %v", err)
+	}
+	cases := make(map[string]junitTestCase)
+	for _, tc := range suites.Suites[0].Cases {
+		cases[strings.Fields(tc.Name)[0]] = tc
+	}
+	for _, id := range []string{RuleManifestSchema, RuleEntranceName, RuleFolderName} {
+		if tc, ok := cases[id]; !ok || tc.Skipped != nil || tc.Failure != nil {
+			t.Errorf("Expected %s to pass, got %+v", id, tc)
+		}
+	}
+	// disabled, of skipped stages and disabled by default
+	for _, id := range []string{RuleSupportArch, RuleContainerResources, RuleVersionConsistency, RuleCategories} {
+		if tc, ok := cases[id]; !ok || tc.Skipped == nil {
+			t.Errorf("Expected %s to be skipped, got %+v", id, tc)
+		}
+	}
+	for id := range cases {
+		if r, _ := LookupRule(id); r.Stage == StageRun || r.Stage == StageSubmission {
+			t.Errorf("Unexpected test case for %s", id)
+		}
+	}
+}
+
+// TestCheckManifestFindings tests the field paths of manifest findings
+func TestCheckManifestFindings(t *testing.T) {
+	cfg, err := GetAppConfiguration("testdata/firefox")
+	if err != nil {
+		t.Fatalf("Failed to get app configuration: %v", err)
+	}
+	cfg.ConfigVersion = ""
+	cfg.Entrances[0].Host = "Invalid_Host"
+
+	findings := checkManifest(cfg)
+	paths := make(map[string]bool)
+	for _, f := range findings {
+		if f.RuleID != RuleManifestSchema {
+			t.Errorf("unexpected rule id %s for finding %v", f.RuleID, f)
+		}
+		paths[f.Path] = true
+	}
+	if !paths["ConfigVersion"] || !paths["Entrances[0].Host"] {
+		t.Errorf("checkManifest returned unexpected paths: %v", paths)
+	}
+}
+
+// TestManifestFindingLocations tests that manifest findings point at the line
+// and column of OlaresManifest.yaml as written, before templating
+func TestManifestFindingLocations(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata/firefox", ManifestName))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	content := strings.Replace(string(data), "  - arm64", "  - sparc", 1)
+	content = strings.Replace(content, "  host: firefox\n", "  host: Invalid_Host\n", 1)
+	lines := strings.Split(content, "\n")
+	lineOf := func(s string) int {
+		for i, l := range lines {
+			if strings.Contains(l, s) {
+				return i + 1
+			}
+		}
+		return 0
+	}
+
+	cfg, locator, err := loadManifest([]byte(content))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	findings := checkManifest(cfg)
+	locator.annotate(findings)
+
+	want := map[string][2]int{
+		"Spec.SupportArch[1]": {lineOf("- sparc"), 5},
+		"Entrances[0].Host":   {lineOf("host: Invalid_Host"), 3},
+	}
+	for _, f := range findings {
+		w, ok := want[f.Path]
+		if !ok {
+			continue
+		}
+		if f.Line != w[0] || f.Column != w[1] {
+			t.Errorf("finding %s at %d:%d, want %d:%d", f.Path, f.Line, f.Column, w[0], w[1])
+		}
+		delete(want, f.Path)
+	}
+	if len(want) > 0 {
+		t.Errorf("missing findings for %v", want)
+	}
+	if line, _ := locator.locate("Options.Dependencies[1].Type"); line != lineOf("type: system") {
+		t.Errorf("Options.Dependencies[1].Type located at line %d, want %d", line, lineOf("type: system"))
+	}
+}
+
+// TestResourceSources tests that rendered resources and their findings point
+// at the template they were rendered from
+func TestResourceSources(t *testing.T) {
+	resources, err := newChartBundle("testdata/firefox", DefaultLintOptions()).resources(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to render chart: %v", err)
+	}
+
+	want := map[string]string{
+		"Deployment/firefox": "templates/firefox.yaml:3",
+		"Service/firefox":    "templates/firefox.yaml:96",
+	}
+	for _, r := range resources {
+		key := r.Object.GetObjectKind().GroupVersionKind().Kind + "/" + r.Name
+		if source, ok := want[key]; ok {
+			if r.Source != source {
+				t.Errorf("%s has source %q, want %q", key, r.Source, source)
+			}
+			delete(want, key)
+		}
+		if key == "Deployment/firefox" {
+			f := findingf(RuleContainerResources, "", "test").withSource(r)
+			if f.File != "templates/firefox.yaml" || f.Line != 3 || f.Kind != "Deployment" || f.Name != "firefox" {
+				t.Errorf("unexpected finding location: %+v", f)
+			}
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing resources %v", want)
+	}
+
+	// the RBAC finding points at the role granting the forbidden verbs, not
+	// at the first role bound to a service account
+	chart := mapChart(t, "testdata/firefox", "firefox")
+	chart["firefox/templates/a-empty.yaml"] = &fstest.MapFile{Data: []byte(`apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: aaa-empty
+rules: []
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: aaa-empty
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: aaa-empty
+subjects:
+- kind: ServiceAccount
+  name: firefox-sa
+`)}
+	chart["firefox/templates/rbac.yaml"] = &fstest.MapFile{Data: []byte(`apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: nodes
+rules:
+- apiGroups: ['*']
+  resources: [nodes]
+  verbs: [create]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: nodes
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: nodes
+subjects:
+- kind: ServiceAccount
+  name: firefox-sa
+`)}
+	b, err := newChartBundleFS(chart, "firefox", DefaultLintOptions())
+	if err != nil {
+		t.Fatalf("Failed to load chart: %v", err)
+	}
+	findings := b.serviceAccountFindings(context.Background())
+	if len(findings) != 1 || findings[0].RuleID != RuleServiceAccountRole || findings[0].File != "templates/rbac.yaml" || findings[0].Name != "nodes" {
+		t.Errorf("Expected one finding on templates/rbac.yaml, got %+v", findings)
+	}
+
+	// a role whose rules can not be read is reported at its template
+	chart["firefox/templates/a-empty.yaml"].Data = bytes.Replace(chart["firefox/templates/a-empty.yaml"].Data,
+		[]byte("rules: []"), []byte("rules: oops"), 1)
+	b, err = newChartBundleFS(chart, "firefox", DefaultLintOptions())
+	if err != nil {
+		t.Fatalf("Failed to load chart: %v", err)
+	}
+	findings = b.serviceAccountFindings(context.Background())
+	if len(findings) != 2 || findings[0].File != "templates/a-empty.yaml" || findings[0].Name != "aaa-empty" ||
+		findings[1].File != "templates/rbac.yaml" {
+		t.Errorf("Expected findings on templates/a-empty.yaml and templates/rbac.yaml, got %+v", findings)
+	}
+}
+
+// TestChartErrors tests that chart folder and manifest failures can be told
+// apart with errors.Is and inspected with errors.As
+func TestChartErrors(t *testing.T) {
+	chartPath := copyTestChart(t, nil)
+	if err := os.Remove(filepath.Join(chartPath, "values.yaml")); err != nil {
+		t.Fatalf("Failed to remove values.yaml: %v", err)
+	}
+	err := CheckChartFolder(chartPath)
+	if !errors.Is(err, ErrMissingValuesYaml) || errors.Is(err, ErrInvalidFolderName) {
+		t.Errorf("CheckChartFolder returned %v, want ErrMissingValuesYaml", err)
+	}
+	var chartErr *ChartError
+	if !errors.As(err, &chartErr) || chartErr.Folder != chartPath || chartErr.Field != "values.yaml" {
+		t.Errorf("unexpected chart error: %+v", chartErr)
+	}
+
+	if err := CheckChartFolder(filepath.Join(t.TempDir(), "Invalid_Name")); !errors.Is(err, ErrInvalidFolderName) {
+		t.Errorf("CheckChartFolder returned %v, want ErrInvalidFolderName", err)
+	}
+
+	chartPath = copyTestChart(t, nil)
+	if err := os.WriteFile(filepath.Join(chartPath, "Chart.yaml"), []byte("name: [firefox"), 0644); err != nil {
+		t.Fatalf("Failed to write Chart.yaml: %v", err)
+	}
+	err = CheckChartFolder(chartPath)
+	if !errors.Is(err, ErrParseChartYaml) || !errors.As(err, &chartErr) || chartErr.Err == nil {
+		t.Errorf("CheckChartFolder returned %v, want ErrParseChartYaml wrapping the yaml error", err)
+	}
+
+	chartPath = copyTestChart(t, func(manifest string) string {
+		return manifest + "spec: [unclosed\n"
+	})
+	err = CheckChartFolder(chartPath)
+	if !errors.Is(err, ErrParseAppCfg) || errors.Is(err, ErrReadAppCfg) || !errors.As(err, &chartErr) || chartErr.Err == nil {
+		t.Errorf("CheckChartFolder returned %v, want ErrParseAppCfg wrapping the yaml error", err)
+	}
+
+	chartPath = copyTestChart(t, func(manifest string) string {
+		return strings.Replace(manifest, "  version: '1.0.1'", "  version: '1.0.2'", 1)
+	})
+	err = CheckSameVersion(chartPath)
+	if !errors.Is(err, ErrVersionMismatch) || !errors.As(err, &chartErr) {
+		t.Fatalf("CheckSameVersion returned %v, want ErrVersionMismatch", err)
+	}
+	if chartErr.Expected == chartErr.Actual || chartErr.Actual != "1.0.2" {
+		t.Errorf("unexpected versions expected=%q actual=%q", chartErr.Expected, chartErr.Actual)
+	}
+
+	cfg, err := GetAppConfiguration("testdata/firefox")
+	if err != nil {
+		t.Fatalf("Failed to get app configuration: %v", err)
+	}
+	cfg.Spec.SupportArch = []string{"amd64", "sparc"}
+	err = CheckSupportedArch(cfg)
+	if !errors.Is(err, ErrUnsupportedArch) || !errors.As(err, &chartErr) || chartErr.Field != "Spec.SupportArch[1]" {
+		t.Errorf("CheckSupportedArch returned %v, want ErrUnsupportedArch for Spec.SupportArch[1]", err)
+	}
+}
+
+// TestMultiError tests that aggregated errors keep every child error
+func TestMultiError(t *testing.T) {
+	cfg, err := GetAppConfiguration("testdata/firefox")
+	if err != nil {
+		t.Fatalf("Failed to get app configuration: %v", err)
+	}
+	cfg.Spec.SupportArch = []string{"sparc", "amd64", "mips"}
+	findings := checkSupportedArch(cfg)
+	plain := errors.New("plain error")
+	err = AggregateErr([]error{findings[1], plain, findings[0], findings[0]})
+
+	if err.Error() != findings[1].Error()+"\n"+plain.Error()+"\n"+findings[0].Error()+"\n"+findings[0].Error()+"\n" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	var m *MultiError
+	if !errors.As(err, &m) || len(m.Unwrap()) != 4 {
+		t.Fatalf("AggregateErr returned %T, want *MultiError with 4 errors", err)
+	}
+	if !errors.Is(err, ErrUnsupportedArch) || !errors.Is(err, plain) {
+		t.Errorf("errors.Is does not see the child errors of %v", err)
+	}
+	var chartErr *ChartError
+	if !errors.As(err, &chartErr) || chartErr.Actual != "mips" {
+		t.Errorf("errors.As returned %+v, want the first chart error", chartErr)
+	}
+
+	m.Dedupe().Sort()
+	if len(m.Errors) != 3 || m.Errors[0] != plain || m.Errors[1] != findings[1] || m.Errors[2] != findings[0] {
+		t.Errorf("unexpected errors after Dedupe and Sort: %v", m.Errors)
+	}
+	if got := asFindings(m, RuleChartRender, ""); len(got) != 3 || got[0].RuleID != RuleChartRender || got[1] != findings[1] {
+		t.Errorf("asFindings did not expand the multi-error: %v", got)
+	}
+
+	if err := CheckSupportedArch(cfg); !errors.As(err, &m) || len(m.Errors) != 2 {
+		t.Errorf("CheckSupportedArch returned %v, want a *MultiError with 2 errors", err)
+	}
+}
+
+// TestLintContext tests that lint runs stop once their context is done
+func TestLintContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := LintReportContext(ctx, "testdata/firefox", DefaultLintOptions())
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != RuleLintCanceled {
+		t.Fatalf("unexpected findings of a canceled lint: %v", report.Findings)
+	}
+	if len(report.Skipped) == 0 || report.Skipped[0].Stage != StageLoad {
+		t.Errorf("canceled lint did not skip its stages: %v", report.Skipped)
+	}
+	if err := LintContext(ctx, "testdata/firefox", DefaultLintOptions()); !errors.Is(err, context.Canceled) {
+		t.Errorf("LintContext returned %v, want context.Canceled", err)
+	}
+
+	cfg, err := GetAppConfiguration("testdata/firefox")
+	if err != nil {
+		t.Fatalf("Failed to get app configuration: %v", err)
+	}
+	if err := CheckResourceContext(ctx, "testdata/firefox", cfg, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckResourceContext returned %v, want context.Canceled", err)
+	}
+	if err := CheckServiceAccountRoleContext(ctx, "testdata/firefox"); !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckServiceAccountRoleContext returned %v, want context.Canceled", err)
+	}
+	cfg.Permission.AppData = false
+	if err := CheckAppDataContext(ctx, "testdata/firefox", cfg); !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckAppDataContext returned %v, want context.Canceled", err)
+	}
+
+	// a validator blocking until the deadline ends the run
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var ran bool
+	options := DefaultLintOptions().SkipResources().
+		WithContextValidator(func(ctx context.Context, _ string, _ *AppConfiguration) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		WithContextValidator(func(context.Context, string, *AppConfiguration) error {
+			ran = true
+			return nil
+		})
+	options.CollectAll = true
+	report = LintReportContext(ctx, "testdata/firefox", options)
+	if err := report.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LintReportContext returned %v, want context.DeadlineExceeded", err)
+	}
+	if ran {
+		t.Error("validator ran after the deadline")
+	}
+
+	// a stage ending as the context is done ran, only the later ones are skipped
+	ctx, cancel = context.WithCancel(context.Background())
+	options = DefaultLintOptions().SkipResources().
+		WithContextValidator(func(context.Context, string, *AppConfiguration) error {
+			cancel()
+			return nil
+		})
+	options.CollectAll = true
+	report = LintReportContext(ctx, "testdata/firefox", options)
+	for _, s := range report.Skipped {
+		if s.Stage == StageCustom {
+			t.Errorf("completed stage %s reported as skipped: %v", s.Stage, report.Skipped)
+		}
+	}
+	if len(report.Skipped) == 0 || report.Skipped[0].Stage != StageFolder {
+		t.Errorf("canceled lint did not skip the stages after %s: %v", StageCustom, report.Skipped)
+	}
+	if n := len(report.Findings); n != 1 || report.Findings[0].RuleID != RuleLintCanceled {
+		t.Errorf("unexpected findings of a canceled lint: %v", report.Findings)
+	}
+}
+
+// TestLoadChartBundle tests that a bundle holds every part of the chart and
+// renders it only once
+func TestLoadChartBundle(t *testing.T) {
+	b, err := LoadChartBundle("testdata/firefox", DefaultLintOptions().WithSameOwnerAndAdmin("alice"))
+	if err != nil {
+		t.Fatalf("LoadChartBundle failed: %v", err)
+	}
+	if b.Folder != "firefox" || b.ChartFile.Name != "firefox" || b.Manifest.Metadata.Name != "firefox" {
+		t.Errorf("unexpected chart names: folder=%s chart=%+v", b.Folder, b.ChartFile)
+	}
+	if len(b.RawManifest) == 0 || !strings.Contains(b.RenderedManifest, "name: firefox") {
+		t.Errorf("manifest not loaded: %q", b.RenderedManifest)
+	}
+	if b.Chart == nil || len(b.Chart.Templates) != 2 || b.Values == nil {
+		t.Errorf("helm chart not loaded: %v", b.Chart)
+	}
+	if locales := b.Locales(); len(locales) != 2 || locales[0] != "en-US" || locales[1] != "zh-CN" {
+		t.Errorf("unexpected locales %v", locales)
+	}
+	if len(b.Owners) != 4 || b.Owners[0] != "LittleLollipop" {
+		t.Errorf("unexpected owners %v", b.Owners)
+	}
+	if len(b.Resources) == 0 {
+		t.Fatal("no resources rendered")
+	}
+	resources, err := b.resources(context.Background())
+	if err != nil || &resources[0] != &b.Resources[0] {
+		t.Errorf("resources were rendered again")
+	}
+
+	if _, err := LoadChartBundle(t.TempDir(), nil); err == nil {
+		t.Error("LoadChartBundle succeeded on an empty folder")
+	}
+}
+
+// TestLintCatalog tests linting every chart of a repository concurrently
+func TestLintCatalog(t *testing.T) {
+	root := t.TempDir()
+	names := []string{"delta", "alpha", "charlie", "bravo"}
+	for _, name := range names {
+		if err := os.MkdirAll(filepath.Join(root, name), 0755); err != nil {
+			t.Fatalf("Failed to create chart directory: %v", err)
+		}
+		if err := copyDir("testdata/firefox", filepath.Join(root, name)); err != nil {
+			t.Fatalf("Failed to copy test chart: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".github"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	options := DefaultLintOptions().SkipResources().WithCollectAll().
+		WithCustomValidator(func(oacPath string, _ *AppConfiguration) error {
+			if filepath.Base(oacPath) == "charlie" {
+				panic("boom")
+			}
+			return nil
+		})
+	options.SkipSameVersionCheck = false
+	reports, err := LintCatalog(root, &CatalogOptions{Options: options, Workers: 3})
+	if err != nil {
+		t.Fatalf("LintCatalog failed: %v", err)
+	}
+	want := []string{"alpha", "bravo", "charlie", "delta"}
+	if len(reports) != len(want) {
+		t.Fatalf("LintCatalog returned %d reports, want %d", len(reports), len(want))
+	}
+	for i, r := range reports {
+		if r.Chart != want[i] {
+			t.Errorf("report %d is for %s, want %s", i, r.Chart, want[i])
+		}
+		panicked := len(r.Findings) == 1 && r.Findings[0].RuleID == RuleLintPanic
+		if panicked != (r.Chart == "charlie") {
+			t.Errorf("unexpected findings for %s: %v", r.Chart, r.Findings)
+		}
+		if r.Chart != "charlie" && !hasRule(r.Findings, RuleNameConsistency) {
+			t.Errorf("missing name consistency finding for %s: %v", r.Chart, r.Findings)
+		}
+	}
+
+	// Test options per chart
+	reports, err = LintCatalog(root, &CatalogOptions{
+		ChartOptions: func(oacPath string) (*LintOptions, error) {
+			if filepath.Base(oacPath) == "bravo" {
+				return nil, errors.New("bad config")
+			}
+			return DefaultLintOptions().SkipResources(), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("LintCatalog failed: %v", err)
+	}
+	for _, r := range reports {
+		if failed := hasRule(r.Findings, RuleConfigLoad); failed != (r.Chart == "bravo") || (!failed && len(r.Findings) > 0) {
+			t.Errorf("unexpected findings for %s: %v", r.Chart, r.Findings)
+		}
+	}
+}
+
+func hasRule(findings []*Finding, ruleID string) bool {
+	for _, f := range findings {
+		if f.RuleID == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+// TestCache tests that unchanged charts reuse the cached report
+func TestCache(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	chartPath := copyTestChart(t, nil)
+	options := DefaultLintOptions().SkipResources().WithCache(cache)
+	options.SkipSameVersionCheck = false
+
+	key, ok, err := cache.Key(chartPath, options)
+	if err != nil || !ok {
+		t.Fatalf("Key failed: %v", err)
+	}
+	first := LintReport(chartPath, options)
+	cached, hit := cache.Get(key)
+	if !hit || len(cached.Findings) != len(first.Findings) {
+		t.Fatalf("report not cached: %v", cached)
+	}
+
+	// a hit returns the stored report instead of linting again
+	cached.Findings = append(cached.Findings, findingf(RuleImages, "", "from cache"))
+	if err := cache.Put(key, cached); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	second := LintReport(chartPath, options)
+	if !hasRule(second.Findings, RuleImages) || second.Path != chartPath {
+		t.Errorf("cached report not used: %v", second.Findings)
+	}
+
+	// Lint does not use the cache, so its errors keep their kinds
+	broken := copyTestChart(t, nil)
+	if err := os.Remove(filepath.Join(broken, "values.yaml")); err != nil {
+		t.Fatalf("Failed to remove values.yaml: %v", err)
+	}
+	LintReport(broken, options)
+	if err := Lint(broken, options); !errors.Is(err, ErrMissingValuesYaml) {
+		t.Errorf("Expected %v with a cached report, got %v", ErrMissingValuesYaml, err)
+	}
+
+	// a changed symlink target changes the key
+	target := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(target, []byte("a: 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write values.yaml: %v", err)
+	}
+	linked := copyTestChart(t, nil)
+	if err := os.Remove(filepath.Join(linked, "values.yaml")); err != nil {
+		t.Fatalf("Failed to remove values.yaml: %v", err)
+	}
+	if err := os.Symlink(target, filepath.Join(linked, "values.yaml")); err != nil {
+		t.Logf("symlinks not supported: %v", err)
+	} else {
+		before, _, _ := cache.Key(linked, options)
+		if err := os.WriteFile(target, []byte("a: 2\n"), 0644); err != nil {
+			t.Fatalf("Failed to write values.yaml: %v", err)
+		}
+		if after, _, _ := cache.Key(linked, options); after == before {
+			t.Error("key did not change with the symlink target")
+		}
+	}
+
+	// content, options and custom validators change the key
+	if err := os.WriteFile(filepath.Join(chartPath, "values.yaml"), []byte("changed: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write values.yaml: %v", err)
+	}
+	if changed, _, _ := cache.Key(chartPath, options); changed == key {
+		t.Error("key did not change with the chart content")
+	}
+	if changed, _, _ := cache.Key(chartPath, DefaultLintOptions().WithOwner("bob")); changed == key {
+		t.Error("key did not change with the options")
+	}
+	validated := DefaultLintOptions().WithCustomValidator(func(string, *AppConfiguration) error { return nil })
+	if _, ok, _ := cache.Key(chartPath, validated); ok {
+		t.Error("options with custom validators are cacheable")
+	}
+
+	if err := cache.Invalidate(key); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+	if _, hit := cache.Get(key); hit {
+		t.Error("invalidated report still cached")
+	}
+
+	// the least recently used report is evicted past the size bound
+	report := &Report{Chart: "firefox"}
+	data, _ := json.Marshal(report)
+	small, err := NewCache(t.TempDir(), int64(len(data))*2)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	for i, k := range []string{"a", "b", "c"} {
+		if err := small.Put(k, report); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		old := time.Now().Add(time.Duration(i-10) * time.Minute)
+		_ = os.Chtimes(small.file(k), old, old)
+	}
+	if _, hit := small.Get("a"); hit {
+		t.Error("oldest report not evicted")
+	}
+	if _, hit := small.Get("c"); !hit {
+		t.Error("newest report evicted")
+	}
+	if err := small.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if _, hit := small.Get("c"); hit {
+		t.Error("report left after Clear")
+	}
+}
+
+// TestMemFS tests the in-memory file system charts of archives are read from
+func TestMemFS(t *testing.T) {
+	fsys := newMemFS(map[string][]byte{
+		"firefox/Chart.yaml":               []byte("name: firefox\n"),
+		"firefox/templates/firefox.yaml":   []byte("kind: Deployment\n"),
+		"firefox/templates/tests/pod.yaml": nil,
+	})
+	if err := fstest.TestFS(fsys, "firefox/Chart.yaml", "firefox/templates/firefox.yaml", "firefox/templates/tests/pod.yaml"); err != nil {
+		t.Error(err)
+	}
+	if _, err := fsys.Open("firefox/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected %v, got %v", fs.ErrNotExist, err)
+	}
+}
+
+// TestLintArchive tests linting a packaged chart and rejecting unsafe archives
+func TestLintArchive(t *testing.T) {
+	chartPath := copyTestChart(t, nil)
+	archive := filepath.Join(t.TempDir(), "firefox-1.0.1.tgz")
+	if err := os.WriteFile(archive, tarChart(t, chartPath, nil), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+
+	options := DefaultLintOptions().WithCollectAll()
+	want := LintReport(chartPath, options)
+	report, err := LintArchive(archive, options)
+	if err != nil {
+		t.Fatalf("LintArchive failed: %v", err)
+	}
+	if report.Chart != "firefox" || report.Path != archive {
+		t.Errorf("Expected chart firefox at %s, got %s at %s", archive, report.Chart, report.Path)
+	}
+	if len(report.Findings) != len(want.Findings) {
+		t.Errorf("Expected %d findings as for the folder, got %d", len(want.Findings), len(report.Findings))
+	}
+	for _, f := range want.Findings {
+		if !hasRule(report.Findings, f.RuleID) {
+			t.Errorf("Expected finding %s of the folder in the archive report", f.RuleID)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		extra map[string]string
+		want  error
+	}{
+		{"parent folder", map[string]string{"firefox/../evil.yaml": "x"}, ErrArchiveUnsafePath},
+		{"absolute path", map[string]string{"/etc/evil.yaml": "x"}, ErrArchiveUnsafePath},
+		{"second chart", map[string]string{"other/Chart.yaml": "x"}, ErrArchiveInvalid},
+		{"oversized entry", map[string]string{"firefox/big.bin": strings.Repeat("x", int(DefaultArchiveLimits.MaxFileSize)+1)}, ErrArchiveTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LintArchiveReader(bytes.NewReader(tarChart(t, chartPath, tt.extra)), options)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// Helper function to package the chart folder at dir like helm package,
+// adding the extra entries as is
+func tarChart(t *testing.T, dir string, extra map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Dir(dir), path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		write(filepath.ToSlash(rel), data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to package chart: %v", err)
+	}
+	for name, data := range extra {
+		write(name, []byte(data))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestLintFS tests that a chart held in an fs.FS lints like the same chart
+// on disk
+func TestLintFS(t *testing.T) {
+	fsys := mapChart(t, "testdata/firefox", "firefox")
+	options := DefaultLintOptions().WithCollectAll()
+	want := LintReport("testdata/firefox", options)
+	report := LintReportFS(context.Background(), fsys, "firefox", options)
+	if report.Chart != "firefox" || report.Path != "firefox" {
+		t.Errorf("Expected chart firefox, got %s at %s", report.Chart, report.Path)
+	}
+	if len(report.Findings) != len(want.Findings) {
+		t.Errorf("Expected %d findings as on disk, got %d", len(want.Findings), len(report.Findings))
+	}
+	for _, f := range want.Findings {
+		if !hasRule(report.Findings, f.RuleID) {
+			t.Errorf("Expected finding %s of the chart on disk", f.RuleID)
+		}
+	}
+
+	// files matched by .helmignore are not rendered
+	fsys["firefox/templates/broken.yaml"] = &fstest.MapFile{Data: []byte("{{ .Values.missing.field }}")}
+	fsys["firefox/.helmignore"] = &fstest.MapFile{Data: []byte("broken.yaml\n")}
+	b, err := LoadChartBundleFS(context.Background(), fsys, "firefox", DefaultLintOptions())
+	if err != nil {
+		t.Fatalf("LoadChartBundleFS failed: %v", err)
+	}
+	for _, tpl := range b.Chart.Templates {
+		if tpl.Name == "templates/broken.yaml" {
+			t.Error("Expected templates/broken.yaml to be ignored")
+		}
+	}
+
+	delete(fsys, "firefox/values.yaml")
+	err = CheckChartFS(context.Background(), fsys, "firefox")
+	if !errors.Is(err, ErrMissingValuesYaml) {
+		t.Errorf("Expected %v, got %v", ErrMissingValuesYaml, err)
+	}
+
+	chart, err := fs.Sub(fsys, "firefox")
+	if err != nil {
+		t.Fatalf("fs.Sub failed: %v", err)
+	}
+	cfg, err := GetAppConfigurationFS(chart)
+	if err != nil {
+		t.Fatalf("GetAppConfigurationFS failed: %v", err)
+	}
+	cfg.Permission.AppData = false
+	fsys["firefox/templates/data.yaml"] = &fstest.MapFile{Data: []byte("path: {{ .Values.userspace.appdata }}\n")}
+	err = CheckAppDataFS(context.Background(), chart, cfg)
+	if !errors.Is(err, ErrAppDataPermission) {
+		t.Errorf("Expected %v, got %v", ErrAppDataPermission, err)
+	}
+}
+
+// TestLintAppDataFS tests the appData check on charts that are not on disk
+func TestLintAppDataFS(t *testing.T) {
+	chartPath := copyTestChart(t, func(manifest string) string {
+		return strings.Replace(manifest, "appData: true", "appData: false", 1)
+	})
+	template := "path: {{ .Values.userspace.appdata }}\n"
+	options := DefaultLintOptions().SkipResources().WithCollectAll()
+	options.WithAppDataValidator()
+
+	fsys := mapChart(t, chartPath, "firefox")
+	fsys["firefox/templates/data.yaml"] = &fstest.MapFile{Data: []byte(template)}
+	report := LintReportFS(context.Background(), fsys, "firefox", options)
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != RuleAppData || report.Findings[0].File != "templates/data.yaml" {
+		t.Errorf("LintReportFS expected an appData finding, got: %+v", report.Findings)
+	}
+
+	data := tarChart(t, chartPath, map[string]string{"firefox/templates/data.yaml": template})
+	report, err := LintArchiveReader(bytes.NewReader(data), options)
+	if err != nil {
+		t.Fatalf("LintArchiveReader failed: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].RuleID != RuleAppData || report.Findings[0].File != "templates/data.yaml" {
+		t.Errorf("LintArchiveReader expected an appData finding, got: %+v", report.Findings)
+	}
+}
+
+// Helper function to read the chart folder at dir into a MapFS, under the
+// folder name
+func mapChart(t *testing.T, dir, name string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fsys[name+"/"+filepath.ToSlash(rel)] = &fstest.MapFile{Data: data}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to read chart: %v", err)
+	}
+	return fsys
+}
+
+// TestLintOCI tests linting a chart pulled from a plain HTTP registry
+func TestLintOCI(t *testing.T) {
+	chartPath := copyTestChart(t, nil)
+	registry := newTestRegistry(t, "charts/firefox", "1.0.1", tarChart(t, chartPath, nil))
+	host := strings.TrimPrefix(registry.URL, "http://")
+	oci := &OCIOptions{PlainHTTP: true, CredentialsFile: filepath.Join(t.TempDir(), "config.json")}
+
+	options := DefaultLintOptions().WithCollectAll()
+	want := LintReport(chartPath, options)
+	ref := "oci://" + host + "/charts/firefox:1.0.1"
+	report, err := LintOCI(context.Background(), ref, oci, options)
+	if err != nil {
+		t.Fatalf("LintOCI failed: %v", err)
+	}
+	if report.Chart != "firefox" || report.Source != ref || report.Path != "firefox" {
+		t.Errorf("Expected chart firefox from %s, got %s at %s from %s", ref, report.Chart, report.Path, report.Source)
+	}
+	if len(report.Findings) > 0 {
+		if p := findingPath(report, report.Findings[0]); strings.Contains(p, "oci:") {
+			t.Errorf("Expected a path within the chart, got %s", p)
+		}
+	}
+	if len(report.Findings) != len(want.Findings) {
+		t.Errorf("Expected %d findings as for the folder, got %d", len(want.Findings), len(report.Findings))
+	}
+
+	if _, err := LintOCI(context.Background(), "oci://"+host+"/charts/firefox:9.9.9", oci, options); err == nil {
+		t.Error("Expected an error for a missing tag")
+	}
+	if _, err := LintOCI(context.Background(), host+"/charts/firefox:1.0.1", oci, options); err == nil {
+		t.Error("Expected an error for a reference without oci://")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := LintOCI(ctx, ref, oci, options); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+// Helper function to serve one Helm chart like an OCI registry at
+// repository:tag
+func newTestRegistry(t *testing.T, repository, tag string, archive []byte) *httptest.Server {
+	blobs := map[string][]byte{}
+	descriptor := func(mediaType string, data []byte) map[string]interface{} {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+		blobs[digest] = data
+		return map[string]interface{}{"mediaType": mediaType, "digest": digest, "size": len(data)}
+	}
+	config := []byte(`{"apiVersion":"v2","name":"firefox","version":"1.0.1"}`)
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config":        descriptor("application/vnd.cncf.helm.config.v1+json", config),
+		"layers":        []interface{}{descriptor("application/vnd.cncf.helm.chart.content.v1.tar+gzip", archive)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode manifest: %v", err)
+	}
+	manifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/v2/" + repository + "/"
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == prefix+"manifests/"+tag || r.URL.Path == prefix+"manifests/"+manifestDigest:
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", manifestDigest)
+			w.Header().Set("Content-Length", fmt.Sprint(len(manifest)))
+			if r.Method != http.MethodHead {
+				w.Write(manifest)
+			}
+		case strings.HasPrefix(r.URL.Path, prefix+"blobs/"):
+			data, ok := blobs[strings.TrimPrefix(r.URL.Path, prefix+"blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			if r.Method != http.MethodHead {
+				w.Write(data)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestParsePRTitle tests parsing PR titles into TitleInfo
+func TestParsePRTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  TitleInfo
+		field string
+	}{
+		{"[NEW][firefox][1.0.1]Add firefox", TitleInfo{PrType: PrTypeNew, Folder: "firefox", Version: "1.0.1", Title: "Add firefox"}, ""},
+		{"  [ update ] [firefox] [ 1.0.2 ]  Bump firefox  ", TitleInfo{PrType: PrTypeUpdate, Folder: "firefox", Version: "1.0.2", Title: "Bump firefox"}, ""},
+		{"[Remove][firefox][1.0.1]", TitleInfo{PrType: PrTypeRemove, Folder: "firefox", Version: "1.0.1"}, ""},
+		{"[SUSPEND][firefox][1.0.1-beta.1] pause", TitleInfo{PrType: PrTypeSuspend, Folder: "firefox", Version: "1.0.1-beta.1", Title: "pause"}, ""},
+		{"Add firefox", TitleInfo{}, ""},
+		{"[NEW][firefox]Add firefox", TitleInfo{}, ""},
+		{"[FIX][firefox][1.0.1]Add firefox", TitleInfo{}, "PrType"},
+		{"[NEW][Firefox][1.0.1]Add firefox", TitleInfo{}, "Folder"},
+		{"[NEW][fire-fox][1.0.1]Add firefox", TitleInfo{}, "Folder"},
+		{"[NEW][firefox][latest]Add firefox", TitleInfo{}, "Version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got, err := ParsePRTitle(tt.title)
+			if tt.want.PrType != "" {
+				if err != nil {
+					t.Fatalf("ParsePRTitle failed: %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("Expected %+v, got %+v", tt.want, got)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidTitle) || err.Error() != TitleInvalid {
+				t.Fatalf("Expected %q, got %v", TitleInvalid, err)
+			}
+			var ce *ChartError
+			if !errors.As(err, &ce) || ce.Field != tt.field {
+				t.Errorf("Expected field %q, got %+v", tt.field, ce)
+			}
+		})
+	}
+
+	// a parsed title feeds CheckChartFolderWithTitle
+	info, err := ParsePRTitle("[UPDATE][firefox][1.0.1]Update firefox")
+	if err != nil {
+		t.Fatalf("ParsePRTitle failed: %v", err)
+	}
+	if err := CheckChartFolderWithTitle(copyTestChart(t, nil), info); errors.Is(err, ErrNameMismatch) || errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("Expected the title to match the chart, got %v", err)
+	}
+}
+
+// TestValidateSubmission tests the change list rules of app store PRs
+func TestValidateSubmission(t *testing.T) {
+	base := fstest.MapFS{
+		"firefox/Chart.yaml": {Data: []byte("name: firefox\n")},
+		"README.md":          {Data: []byte("apps\n")},
+	}
+	tests := []struct {
+		name    string
+		title   string
+		changed []string
+		rules   []string
+	}{
+		{"new", "[NEW][chrome][1.0.0]", []string{"chrome/Chart.yaml", "chrome/OlaresManifest.yaml"}, nil},
+		{"new existing", "[NEW][firefox][1.0.2]", []string{"firefox/Chart.yaml"}, []string{RulePrFolderExists}},
+		{"new control file", "[NEW][chrome][1.0.0]", []string{"chrome/Chart.yaml", "chrome/.suspend"}, []string{RulePrControlFiles}},
+		{"update", "[UPDATE][firefox][1.0.2]", []string{"./firefox/Chart.yaml", "firefox/templates/deployment.yaml"}, nil},
+		{"update missing", "[UPDATE][chrome][1.0.1]", []string{"chrome/Chart.yaml"}, []string{RulePrFolderMissing}},
+		{"update control file", "[UPDATE][firefox][1.0.2]", []string{"firefox/.remove"}, []string{RulePrControlFiles}},
+		{"multiple folders", "[UPDATE][firefox][1.0.2]", []string{"firefox/Chart.yaml", "README.md"}, []string{RulePrMultiDir}},
+		{"folder mismatch", "[UPDATE][chrome][1.0.2]", []string{"firefox/Chart.yaml"}, []string{RulePrFolderMismatch}},
+		{"remove", "[REMOVE][firefox][1.0.1]", []string{"firefox/.remove"}, nil},
+		{"remove other files", "[REMOVE][firefox][1.0.1]", []string{"firefox/.remove", "firefox/Chart.yaml"}, []string{RulePrControlFiles}},
+		{"remove missing", "[REMOVE][chrome][1.0.1]", []string{"chrome/.remove"}, []string{RulePrFolderMissing}},
+		{"suspend", "[SUSPEND][firefox][1.0.1]", []string{"firefox/.suspend", "firefox/Chart.yaml"}, nil},
+		{"suspend without file", "[SUSPEND][firefox][1.0.1]", []string{"firefox/Chart.yaml"}, []string{RulePrControlFiles}},
+		{"suspend and remove", "[SUSPEND][firefox][1.0.1]", []string{"firefox/.suspend", "firefox/.remove"}, []string{RulePrControlFiles}},
+	}
+	kinds := map[string]error{
+		RulePrFolderExists:   ErrFolderExists,
+		RulePrFolderMissing:  ErrFolderNotExist,
+		RulePrControlFiles:   ErrControlFiles,
+		RulePrMultiDir:       ErrMultipleFolders,
+		RulePrFolderMismatch: ErrFolderMismatch,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, err := ParsePRTitle(tt.title)
+			if err != nil {
+				t.Fatalf("ParsePRTitle failed: %v", err)
+			}
+			findings := ValidateSubmission(&Submission{Title: title, Changed: tt.changed, Base: base})
+			var got []string
+			for _, f := range findings {
+				got = append(got, f.RuleID)
+				if !errors.Is(f, kinds[f.RuleID]) {
+					t.Errorf("Expected %s finding of kind %v, got %v", f.RuleID, kinds[f.RuleID], f)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.rules) {
+				t.Errorf("Expected rules %v, got %v", tt.rules, findings)
+			}
+		})
+	}
+
+	title, _ := ParsePRTitle("[UPDATE][firefox][1.0.2]")
+	findings := ValidateSubmission(&Submission{Title: title, Changed: []string{"firefox/Chart.yaml", "chrome/Chart.yaml"}, Base: base})
+	if len(findings) != 1 || findings[0].Message != fmt.Sprintf(PrMultiDir, []string{"chrome", "firefox"}) {
+		t.Errorf("Expected the folders in the message, got %v", findings)
+	}
+}
+
+// TestOwners tests loading, validating and authorizing against owners files
+func TestOwners(t *testing.T) {
+	owners, err := LoadOwners(os.DirFS("testdata"), "firefox")
+	if err != nil {
+		t.Fatalf("LoadOwners failed: %v", err)
+	}
+	if err := owners.Validate("firefox"); err != nil {
+		t.Errorf("Expected valid owners, got %v", err)
+	}
+	if err := owners.Authorize("firefox", "tshentu"); err != nil {
+		t.Errorf("Expected an owner to be authorized, got %v", err)
+	}
+	if err := owners.Authorize("firefox", "mallory"); !errors.Is(err, ErrPermission) || err.Error() != fmt.Sprintf(PrPermission, "firefox") {
+		t.Errorf("Expected %q, got %v", fmt.Sprintf(PrPermission, "firefox"), err)
+	}
+	if _, err := LoadOwners(os.DirFS("testdata"), "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing owners file, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		kind    error
+		actual  string
+	}{
+		{"empty", "owners: []\n", ErrNoOwners, ""},
+		{"no list", "maintainers:\n- alice\n", ErrNoOwners, ""},
+		{"malformed login", "owners:\n- alice\n- 'bob smith'\n", ErrInvalidOwner, "bob smith"},
+		{"leading hyphen", "owners:\n- -alice\n", ErrInvalidOwner, "-alice"},
+		{"double hyphen", "owners:\n- al--ice\n", ErrInvalidOwner, "al--ice"},
+		{"too long", "owners:\n- " + strings.Repeat("a", 40) + "\n", ErrInvalidOwner, strings.Repeat("a", 40)},
+		{"duplicate", "owners:\n- alice\n- bob\n- Alice\n", ErrInvalidOwner, "Alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owners, err := ParseOwners([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("ParseOwners failed: %v", err)
+			}
+			err = owners.Validate("firefox")
+			var ce *ChartError
+			if !errors.Is(err, tt.kind) || !errors.As(err, &ce) || ce.Actual != tt.actual {
+				t.Errorf("Expected %v for %q, got %+v", tt.kind, tt.actual, err)
+			}
+			if err := owners.Authorize("firefox", "alice"); !errors.Is(err, tt.kind) {
+				t.Errorf("Expected Authorize to fail with %v, got %v", tt.kind, err)
+			}
+		})
+	}
+}
+
+// TestValidateSubmissionOwners tests the permission rules of owners changes
+func TestValidateSubmissionOwners(t *testing.T) {
+	owners := func(logins ...string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("owners:\n- " + strings.Join(logins, "\n- ") + "\n")}
+	}
+	base := fstest.MapFS{"firefox/owners": owners("alice", "bob")}
+	title, _ := ParsePRTitle("[UPDATE][firefox][1.0.2]")
+	tests := []struct {
+		name    string
+		actor   string
+		changed []string
+		head    fstest.MapFS
+		rules   []string
+	}{
+		{"owner", "Alice", []string{"firefox/owners"}, fstest.MapFS{"firefox/owners": owners("alice", "carol")}, nil},
+		{"not an owner", "mallory", []string{"firefox/owners"}, fstest.MapFS{"firefox/owners": owners("mallory")}, []string{RulePrPermission}},
+		{"no actor", "", []string{"firefox/owners"}, fstest.MapFS{"firefox/owners": owners("alice")}, []string{RulePrPermission}},
+		{"owners untouched", "mallory", []string{"firefox/Chart.yaml"}, nil, nil},
+		{"invalid head", "bob", []string{"firefox/owners"}, fstest.MapFS{"firefox/owners": owners("bob", "bob")}, []string{RulePrOwners}},
+		{"removed", "bob", []string{"firefox/owners"}, fstest.MapFS{}, []string{RulePrOwners}},
+		{"no head", "bob", []string{"firefox/owners"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Submission{Title: title, Changed: tt.changed, Base: base, Actor: tt.actor}
+			if tt.head != nil {
+				s.Head = tt.head
+			}
+			var got []string
+			for _, f := range ValidateSubmission(s) {
+				got = append(got, f.RuleID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.rules) {
+				t.Errorf("Expected rules %v, got %v", tt.rules, got)
+			}
+		})
+	}
+}
+
+// TestCheckVersionIncrease tests the version rules between base and head charts
+func TestCheckVersionIncrease(t *testing.T) {
+	base := copyTestChart(t, nil)
+	// head returns a copy of the test chart at version with versionName and
+	// upgradeDescription set in OlaresManifest.yaml
+	head := func(version, manifestVersion, versionName, upgradeDescription string) string {
+		chartPath := copyTestChart(t, func(manifest string) string {
+			manifest = strings.Replace(manifest, "version: '1.0.1'", "version: '"+manifestVersion+"'", 1)
+			manifest = strings.Replace(manifest, "versionName: '131.0.3'", "versionName: '"+versionName+"'", 1)
+			if upgradeDescription != "" {
+				manifest = strings.Replace(manifest, "spec:\n", "spec:\n  upgradeDescription: '"+upgradeDescription+"'\n", 1)
+			}
+			return manifest
+		})
+		chartYaml := filepath.Join(chartPath, "Chart.yaml")
+		data, err := os.ReadFile(chartYaml)
+		if err != nil {
+			t.Fatalf("Failed to read Chart.yaml: %v", err)
+		}
+		data = []byte(strings.Replace(string(data), "version: 1.0.1", "version: "+version, 1))
+		if err := os.WriteFile(chartYaml, data, 0644); err != nil {
+			t.Fatalf("Failed to write Chart.yaml: %v", err)
+		}
+		return chartPath
+	}
+
+	tests := []struct {
+		name  string
+		head  string
+		rules []string
+	}{
+		{"bump", head("1.0.2", "1.0.2", "132.0.0", "Firefox 132"), nil},
+		{"bump same versionName", head("1.1.0", "1.1.0", "131.0.3", "Rebuilt image"), nil},
+		{"unchanged", head("1.0.1", "1.0.1", "131.0.3", ""), []string{RulePrVersionIncrease, RulePrVersionIncrease}},
+		{"downgrade", head("1.0.0", "1.0.0", "131.0.3", "Revert"), []string{RulePrVersionIncrease, RulePrVersionIncrease}},
+		{"prerelease", head("1.0.1-rc.1", "1.0.1-rc.1", "131.0.3", "Preview"), []string{RulePrVersionIncrease, RulePrVersionIncrease}},
+		{"manifest only", head("1.0.1", "1.0.2", "131.0.3", "Firefox 132"), []string{RulePrVersionIncrease}},
+		{"versionName without bump", head("1.0.1", "1.0.1", "132.0.0", ""), []string{RulePrVersionIncrease, RulePrVersionIncrease, RulePrVersionName}},
+		{"bump without upgradeDescription", head("1.0.2", "1.0.2", "132.0.0", ""), []string{RulePrUpgradeDesc}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckVersionIncrease(base, tt.head)
+			var got []string
+			for _, f := range asFindings(err, "", "") {
+				got = append(got, f.RuleID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.rules) {
+				t.Errorf("Expected rules %v, got %v", tt.rules, err)
+			}
+		})
+	}
+
+	err := CheckVersionIncrease(base, head("1.0.0", "1.0.0", "131.0.3", ""))
+	var ce *ChartError
+	if !errors.Is(err, ErrVersionNotIncreased) || !errors.As(err, &ce) || ce.Expected != "1.0.1" || ce.Actual != "1.0.0" ||
+		!strings.Contains(err.Error(), fmt.Sprintf(PrVersionMustIncrease, "1.0.0", "1.0.1")) {
+		t.Errorf("Expected %q, got %+v", fmt.Sprintf(PrVersionMustIncrease, "1.0.0", "1.0.1"), err)
+	}
+
+	// only the versions of the base are read, whatever its folder is called
+	// and whatever rules it breaks
+	checkout := filepath.Join(t.TempDir(), "base-checkout")
+	if err := os.MkdirAll(checkout, 0755); err != nil {
+		t.Fatalf("Failed to create base chart: %v", err)
+	}
+	if err := copyDir(base, checkout); err != nil {
+		t.Fatalf("Failed to copy base chart: %v", err)
+	}
+	if err := os.Remove(filepath.Join(checkout, "values.yaml")); err != nil {
+		t.Fatalf("Failed to remove values.yaml: %v", err)
+	}
+	if err := CheckVersionIncrease(checkout, head("1.0.2", "1.0.2", "132.0.0", "Firefox 132")); err != nil {
+		t.Errorf("Expected the base-checkout folder to be accepted, got %v", err)
+	}
+	if err := CheckVersionIncrease(checkout, head("1.0.1", "1.0.1", "131.0.3", "")); !errors.Is(err, ErrVersionNotIncreased) {
+		t.Errorf("Expected %v against the base-checkout folder, got %v", ErrVersionNotIncreased, err)
+	}
+
+	// the same check on the repositories of a submission
+	headPath := head("1.0.2", "1.0.2", "131.0.3", "")
+	err = CheckVersionIncreaseFS(os.DirFS(filepath.Dir(base)), os.DirFS(filepath.Dir(headPath)), "firefox")
+	var f *Finding
+	if !errors.Is(err, ErrMissingUpgradeDesc) || !errors.As(err, &f) || f.Line == 0 {
+		t.Errorf("Expected a located %s finding, got %+v", RulePrUpgradeDesc, err)
+	}
+}
+
+// Helper function to copy the test chart into a folder named firefox and
+// apply edit to its OlaresManifest.yaml
+func copyTestChart(t *testing.T, edit func(manifest string) string) string {
+	chartPath := filepath.Join(t.TempDir(), "firefox")
+	if err := os.MkdirAll(chartPath, 0755); err != nil {
+		t.Fatalf("Failed to create chart directory: %v", err)
+	}
+	if err := copyDir("testdata/firefox", chartPath); err != nil {
+		t.Fatalf("Failed to copy test chart: %v", err)
+	}
+	if edit != nil {
+		manifestPath := filepath.Join(chartPath, ManifestName)
+		data, err := os.ReadFile(manifestPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		if err := os.WriteFile(manifestPath, []byte(edit(string(data))), 0644); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+	return chartPath
+}
+
 // Helper function to create a temporary test chart
 func createTempTestChart(t *testing.T) string {
 	tempDir, err := os.MkdirTemp("", "chart-test-*")
@@ -227,3 +1944,19 @@ func copyDir(src, dst string) error {
 
 	return nil
 }
+
+// BenchmarkCheckChart measures a full CheckChart run on the test chart
+func BenchmarkCheckChart(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = CheckChart("testdata/firefox")
+	}
+}
+
+// BenchmarkLint measures a collect-all lint run on the test chart
+func BenchmarkLint(b *testing.B) {
+	options := DefaultLintOptions().WithCollectAll()
+	options.SkipSameVersionCheck = false
+	for i := 0; i < b.N; i++ {
+		_ = LintReport("testdata/firefox", options)
+	}
+}
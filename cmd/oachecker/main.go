@@ -0,0 +1,535 @@
+// Command oachecker lints Olares application charts.
+//
+// Usage:
+//
+//	oachecker lint [flags] path...
+//	oachecker manifest [flags] path
+//	oachecker render [flags] path
+//	oachecker rules [flags]
+//	oachecker version
+//
+// A lint path is a chart folder, a chart archive (.tgz) or an OCI reference
+// (oci://host/repository:tag). The exit code is 0 when no finding reached
+// -fail-on, 1 when one did, 2 for invalid usage and 3 when a chart could not
+// be read at all.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/beclab/oachecker"
+)
+
+// exit codes
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitUsage    = 2
+	exitFailure  = 3
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+	cmd := &command{stdin: stdin, stdout: stdout, stderr: stderr}
+	switch args[0] {
+	case "lint":
+		return cmd.lint(args[1:])
+	case "manifest":
+		return cmd.manifest(args[1:])
+	case "render":
+		return cmd.render(args[1:])
+	case "rules":
+		return cmd.rules(args[1:])
+	case "version":
+		fmt.Fprintln(stdout, "oachecker", oachecker.Version)
+		return exitOK
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "oachecker: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: oachecker <command> [flags] [arguments]
+
+Commands:
+  lint      lint chart folders, chart archives or OCI references
+  manifest  validate only the OlaresManifest.yaml of a chart
+  render    print OlaresManifest.yaml rendered with the fake values
+  rules     list the rules of the linter
+  version   print the version
+
+Run oachecker <command> -h for the flags of a command.
+`)
+}
+
+type command struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// listFlag is a flag taking comma separated values, repeatable.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// ruleListFlag is a listFlag of rule ids rejecting unknown ones, so a typo
+// does not silently leave a rule as it is.
+type ruleListFlag struct{ listFlag }
+
+func (l *ruleListFlag) Set(s string) error {
+	var ids listFlag
+	ids.Set(s)
+	for _, id := range ids {
+		if _, ok := oachecker.LookupRule(id); !ok {
+			return fmt.Errorf("unknown rule %s", id)
+		}
+	}
+	l.listFlag = append(l.listFlag, ids...)
+	return nil
+}
+
+// optionFlags are the flags mirroring LintOptions.
+type optionFlags struct {
+	owner, admin    string
+	skipManifest    bool
+	skipResources   bool
+	skipFolder      bool
+	checkSameVer    bool
+	all             bool
+	enable, disable ruleListFlag
+	noConfig        bool
+}
+
+func (o *optionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.owner, "owner", "", "owner to render the chart with")
+	fs.StringVar(&o.admin, "admin", "", "admin to render the chart with")
+	fs.BoolVar(&o.skipManifest, "skip-manifest", false, "skip the OlaresManifest.yaml checks")
+	fs.BoolVar(&o.skipResources, "skip-resources", false, "skip the checks of the rendered resources")
+	fs.BoolVar(&o.skipFolder, "skip-folder", false, "skip the chart folder checks")
+	fs.BoolVar(&o.checkSameVer, "same-version", false, "check that Chart.yaml and OlaresManifest.yaml agree on name and version")
+	fs.BoolVar(&o.all, "all", false, "run every stage and report all problems, even after a stage failed")
+	fs.Var(&o.enable, "enable", "rule ids to enable, comma separated")
+	fs.Var(&o.disable, "disable", "rule ids to disable, comma separated")
+	fs.BoolVar(&o.noConfig, "no-config", false, "ignore "+oachecker.ConfigFileName+" files")
+}
+
+// options returns the lint options for the chart folder at dir, the
+// configuration files applying to it overridden by the flags. dir is empty
+// for charts not on disk.
+func (o *optionFlags) options(dir string) (*oachecker.LintOptions, error) {
+	options := oachecker.DefaultLintOptions()
+	if dir != "" && !o.noConfig {
+		var err error
+		if options, err = oachecker.LintOptionsForChart(dir); err != nil {
+			return nil, err
+		}
+	}
+	if o.owner != "" {
+		options.WithOwner(o.owner)
+	}
+	if o.admin != "" {
+		options.WithAdmin(o.admin)
+	}
+	options.SkipManifestCheck = options.SkipManifestCheck || o.skipManifest
+	options.SkipResourceCheck = options.SkipResourceCheck || o.skipResources
+	options.SkipFolderCheck = options.SkipFolderCheck || o.skipFolder
+	if o.checkSameVer {
+		options.SkipSameVersionCheck = false
+	}
+	options.CollectAll = options.CollectAll || o.all
+	options.EnableRule(o.enable.listFlag...)
+	options.DisableRule(o.disable.listFlag...)
+	return options, nil
+}
+
+// newFlagSet returns a flag set printing its usage to c.stderr.
+func (c *command) newFlagSet(name, args, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: oachecker %s [flags] %s\n\n%s\n\nFlags:\n", name, args, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args into fs and returns the exit code to stop with, or -1 to
+// go on.
+func parse(fs *flag.FlagSet, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	return -1
+}
+
+func (c *command) lint(args []string) int {
+	fs := c.newFlagSet("lint", "path...",
+		"Lint chart folders, chart archives (.tgz) and OCI references (oci://host/repository:tag).")
+	var opts optionFlags
+	opts.register(fs)
+	format := fs.String("format", oachecker.FormatText, "output format, one of "+strings.Join(oachecker.Formats, ", "))
+	output := fs.String("o", "", "write the report to this file instead of stdout")
+	catalog := fs.Bool("catalog", false, "lint every chart folder under each path")
+	jobs := fs.Int("jobs", 0, "charts of a -catalog linted at the same time, 0 for one per CPU")
+	baselineFile := fs.String("baseline", "", "hide the findings recorded in this baseline file")
+	useCache := fs.Bool("cache", false, "reuse reports of unchanged charts from the user cache directory")
+	failOn := fs.String("fail-on", string(oachecker.SeverityError), "lowest severity failing the run: error, warning or info")
+	timeout := fs.Duration("timeout", 0, "give up after this long, 0 for no limit")
+	plainHTTP := fs.Bool("plain-http", false, "pull OCI charts over HTTP")
+	insecure := fs.Bool("insecure", false, "accept any TLS certificate of OCI registries")
+	if code := parse(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(c.stderr, "oachecker lint: no path given")
+		fs.Usage()
+		return exitUsage
+	}
+	threshold, ok := severityRank[oachecker.Severity(*failOn)]
+	if !ok {
+		fmt.Fprintf(c.stderr, "oachecker lint: invalid -fail-on %q\n", *failOn)
+		return exitUsage
+	}
+	if _, err := oachecker.NewReporter(*format, io.Discard); err != nil {
+		fmt.Fprintf(c.stderr, "oachecker lint: %v\n", err)
+		return exitUsage
+	}
+
+	var baseline *oachecker.Baseline
+	if *baselineFile != "" {
+		var err error
+		if baseline, err = oachecker.LoadBaseline(*baselineFile); err != nil {
+			fmt.Fprintf(c.stderr, "oachecker lint: %v\n", err)
+			return exitFailure
+		}
+	}
+	var cache *oachecker.Cache
+	if *useCache {
+		dir, err := oachecker.DefaultCacheDir()
+		if err == nil {
+			cache, err = oachecker.NewCache(dir, 0)
+		}
+		if err != nil {
+			fmt.Fprintf(c.stderr, "oachecker lint: %v\n", err)
+			return exitFailure
+		}
+	}
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	oci := &oachecker.OCIOptions{PlainHTTP: *plainHTTP, InsecureSkipTLSVerify: *insecure}
+
+	code := exitOK
+	var reports []*oachecker.Report
+	for _, arg := range fs.Args() {
+		if *catalog {
+			catalogReports, err := oachecker.LintCatalogContext(ctx, arg, &oachecker.CatalogOptions{
+				ChartOptions: func(dir string) (*oachecker.LintOptions, error) {
+					return lintOptions(dir, &opts, baseline, cache)
+				},
+				Workers: *jobs,
+			})
+			if err != nil {
+				fmt.Fprintf(c.stderr, "oachecker lint: %v\n", err)
+				code = exitFailure
+				continue
+			}
+			reports = append(reports, catalogReports...)
+			continue
+		}
+		report, err := lintPath(ctx, arg, &opts, oci, baseline, cache)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "oachecker lint: %s: %v\n", arg, err)
+			code = exitFailure
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	if err := c.write(*output, *format, reports); err != nil {
+		fmt.Fprintf(c.stderr, "oachecker lint: %v\n", err)
+		return exitFailure
+	}
+	if code == exitOK && failed(reports, threshold) {
+		code = exitFindings
+	}
+	return code
+}
+
+// lintOptions returns the options of the chart folder dir, empty for charts
+// not on disk, with the flags, baseline and cache applied.
+func lintOptions(dir string, opts *optionFlags, baseline *oachecker.Baseline, cache *oachecker.Cache) (*oachecker.LintOptions, error) {
+	options, err := opts.options(dir)
+	if err != nil {
+		return nil, err
+	}
+	if baseline != nil {
+		options.WithBaseline(baseline)
+	}
+	if cache != nil {
+		options.WithCache(cache)
+	}
+	return options, nil
+}
+
+// lintPath lints one chart folder, archive or OCI reference.
+func lintPath(ctx context.Context, path string, opts *optionFlags, oci *oachecker.OCIOptions,
+	baseline *oachecker.Baseline, cache *oachecker.Cache) (*oachecker.Report, error) {
+	dir := ""
+	if !isArchive(path) && !strings.HasPrefix(path, "oci://") {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("not a chart folder, .tgz archive or oci:// reference")
+		}
+		dir = path
+	}
+	options, err := lintOptions(dir, opts, baseline, cache)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(path, "oci://"):
+		return oachecker.LintOCI(ctx, path, oci, options)
+	case dir == "":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		report, err := oachecker.LintArchiveContext(ctx, f, options)
+		if report != nil {
+			report.Path = path
+		}
+		return report, err
+	}
+	return oachecker.LintReportContext(ctx, dir, options), nil
+}
+
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz")
+}
+
+var severityRank = map[oachecker.Severity]int{
+	oachecker.SeverityInfo:    0,
+	oachecker.SeverityWarning: 1,
+	oachecker.SeverityError:   2,
+}
+
+// failed reports whether a finding of reports has at least the severity
+// rank threshold.
+func failed(reports []*oachecker.Report, threshold int) bool {
+	for _, report := range reports {
+		for _, f := range report.Findings {
+			if severityRank[f.Severity] >= threshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// write reports reports in format to the file output, stdout when empty.
+func (c *command) write(output, format string, reports []*oachecker.Report) error {
+	w := c.stdout
+	var f *os.File
+	if output != "" {
+		var err error
+		if f, err = os.Create(output); err != nil {
+			return err
+		}
+		w = f
+	}
+	reporter, err := oachecker.NewReporter(format, w)
+	if err == nil {
+		err = reporter.Report(reports)
+	}
+	if f != nil {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// readManifest reads OlaresManifest.yaml from a chart folder, the file
+// itself or stdin for "-", and returns its content with the chart name and
+// the folder findings are reported under.
+func (c *command) readManifest(path string) (content []byte, chart, dir string, err error) {
+	if path == "-" {
+		content, err = io.ReadAll(c.stdin)
+		return content, "stdin", "", err
+	}
+	dir = path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+		content, err = os.ReadFile(path)
+		return content, filepath.Base(filepath.Clean(dir)), dir, err
+	}
+	content, err = os.ReadFile(filepath.Join(path, oachecker.ManifestName))
+	return content, filepath.Base(filepath.Clean(dir)), dir, err
+}
+
+func (c *command) manifest(args []string) int {
+	fs := c.newFlagSet("manifest", "path",
+		"Validate only the OlaresManifest.yaml of a chart folder, the file itself or stdin (-).")
+	var opts optionFlags
+	opts.register(fs)
+	format := fs.String("format", oachecker.FormatText, "output format, one of "+strings.Join(oachecker.Formats, ", "))
+	if code := parse(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(c.stderr, "oachecker manifest: expected one path")
+		fs.Usage()
+		return exitUsage
+	}
+	content, chart, dir, err := c.readManifest(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(c.stderr, "oachecker manifest: %v\n", err)
+		return exitFailure
+	}
+	options, err := opts.options(dir)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "oachecker manifest: %v\n", err)
+		return exitFailure
+	}
+	report := oachecker.LintManifestReport(chart, content, options)
+	report.Path = dir
+	if err := c.write("", *format, []*oachecker.Report{report}); err != nil {
+		fmt.Fprintf(c.stderr, "oachecker manifest: %v\n", err)
+		return exitFailure
+	}
+	if report.HasErrors() {
+		return exitFindings
+	}
+	return exitOK
+}
+
+func (c *command) render(args []string) int {
+	fs := c.newFlagSet("render", "path",
+		"Print the OlaresManifest.yaml of a chart folder, the file itself or stdin (-) rendered with the fake values the linter uses,\n"+
+			"followed for a chart folder by its templates rendered with the fake values of the Helm dry run.")
+	owner := fs.String("owner", "", "owner to render with")
+	admin := fs.String("admin", "", "admin to render with")
+	if code := parse(fs, args); code >= 0 {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(c.stderr, "oachecker render: expected one path")
+		fs.Usage()
+		return exitUsage
+	}
+	path := fs.Arg(0)
+	content, _, _, err := c.readManifest(path)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "oachecker render: %v\n", err)
+		return exitFailure
+	}
+	rendered, err := oachecker.RenderManifestFromContent(content, oachecker.WithOwner(*owner), oachecker.WithAdmin(*admin))
+	if err != nil {
+		fmt.Fprintf(c.stderr, "oachecker render: %v\n", err)
+		return exitFindings
+	}
+	c.printYAML(rendered)
+	if info, err := os.Stat(path); path == "-" || err != nil || !info.IsDir() {
+		return exitOK
+	}
+
+	options, err := oachecker.LintOptionsForChart(path)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "oachecker render: %v\n", err)
+		return exitFailure
+	}
+	if *owner != "" {
+		options.WithOwner(*owner)
+	}
+	if *admin != "" {
+		options.WithAdmin(*admin)
+	}
+	manifest, err := oachecker.RenderChart(context.Background(), path, options)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "oachecker render: %v\n", err)
+		return exitFindings
+	}
+	c.printYAML(manifest)
+	return exitOK
+}
+
+// printYAML prints content to c.stdout, ending with a newline.
+func (c *command) printYAML(content string) {
+	fmt.Fprint(c.stdout, content)
+	if !strings.HasSuffix(content, "\n") {
+		fmt.Fprintln(c.stdout)
+	}
+}
+
+func (c *command) rules(args []string) int {
+	fs := c.newFlagSet("rules", "", "List the rules of the linter with their default severity.")
+	format := fs.String("format", oachecker.FormatText, "output format, text or json")
+	if code := parse(fs, args); code >= 0 {
+		return code
+	}
+	rules := oachecker.Rules()
+	switch *format {
+	case oachecker.FormatJSON:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rules); err != nil {
+			fmt.Fprintf(c.stderr, "oachecker rules: %v\n", err)
+			return exitFailure
+		}
+		c.stdout.Write(buf.Bytes())
+	case oachecker.FormatText:
+		tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSTAGE\tSEVERITY\tENABLED")
+		for _, r := range rules {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", r.ID, r.Name, r.Stage, r.Severity, r.Enabled)
+		}
+		tw.Flush()
+	default:
+		fmt.Fprintf(c.stderr, "oachecker rules: unknown format %q, must be text or json\n", *format)
+		return exitUsage
+	}
+	return exitOK
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/beclab/oachecker"
+)
+
+const testChart = "../../testdata/firefox"
+
+// TestRun tests the exit codes and output of the subcommands
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+	}{
+		{"no command", nil, "", exitUsage, ""},
+		{"unknown command", []string{"frobnicate"}, "", exitUsage, ""},
+		{"version", []string{"version"}, "", exitOK, oachecker.Version},
+		{"lint clean", []string{"lint", "-skip-resources", testChart}, "", exitOK, "firefox: no problems found"},
+		{"lint disabled rule", []string{"lint", "-disable", "OAC-RES-004", testChart}, "", exitOK, "no problems found"},
+		{"lint unknown rule", []string{"lint", "-disable", "OAC-RES-04", testChart}, "", exitUsage, ""},
+		{"lint findings", []string{"lint", testChart}, "", exitFindings, "[OAC-RES-004]"},
+		{"lint catalog", []string{"lint", "-catalog", "-jobs", "2", "-skip-resources", filepath.Dir(testChart)}, "", exitOK, "firefox: no problems found"},
+		{"lint missing path", []string{"lint", "testdata/missing"}, "", exitFailure, ""},
+		{"lint no path", []string{"lint"}, "", exitUsage, ""},
+		{"lint bad flag", []string{"lint", "-bogus", testChart}, "", exitUsage, ""},
+		{"lint bad format", []string{"lint", "-format", "xml", testChart}, "", exitUsage, ""},
+		{"lint bad fail-on", []string{"lint", "-fail-on", "fatal", testChart}, "", exitUsage, ""},
+		{"manifest folder", []string{"manifest", testChart}, "", exitOK, "no problems found"},
+		{"manifest file", []string{"manifest", filepath.Join(testChart, oachecker.ManifestName)}, "", exitOK, "no problems found"},
+		{"manifest stdin", []string{"manifest", "-"}, "spec:\n  supportArch: [sparc]\n", exitFindings, "unsupport arch: sparc [OAC-MAN-003]"},
+		{"render", []string{"render", "-owner", "alice", testChart}, "", exitOK, "name: firefox"},
+		{"render templates", []string{"render", testChart}, "", exitOK, "# Source: firefox/templates/firefox.yaml"},
+		{"render manifest file", []string{"render", filepath.Join(testChart, oachecker.ManifestName)}, "", exitOK, "name: firefox"},
+		{"render invalid", []string{"render", "-"}, "{{ .Values.missing.field }}", exitFindings, ""},
+		{"rules", []string{"rules"}, "", exitOK, "OAC-MAN-003"},
+		{"help", []string{"lint", "-h"}, "", exitOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Errorf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", tt.code, code, stdout.String(), stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("Expected output containing %q, got %q", tt.stdout, stdout.String())
+			}
+		})
+	}
+}
+
+// TestRunFormats tests the machine readable outputs
+func TestRunFormats(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"lint", "-all", "-format", "json", testChart}, nil, &stdout, &stderr); code != exitFindings {
+		t.Fatalf("Expected exit code %d, got %d: %s", exitFindings, code, stderr.String())
+	}
+	var reports []*oachecker.Report
+	if err := json.Unmarshal(stdout.Bytes(), &reports); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Chart != "firefox" || len(reports[0].Findings) == 0 {
+		t.Errorf("Expected one firefox report with findings, got %+v", reports)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"rules", "-format", "json"}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	var rules []oachecker.Rule
+	if err := json.Unmarshal(stdout.Bytes(), &rules); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(rules) != len(oachecker.Rules()) {
+		t.Errorf("Expected %d rules, got %d", len(oachecker.Rules()), len(rules))
+	}
+
+	output := filepath.Join(t.TempDir(), "report.sarif")
+	if code := run([]string{"lint", "-skip-resources", "-format", "sarif", "-o", output, testChart}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Report not written: %v", err)
+	}
+	var log oachecker.SARIFLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Errorf("Invalid SARIF output: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package oachecker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the name of the project level configuration file.
+const ConfigFileName = ".oachecker.yaml"
+
+// Config is the content of a .oachecker.yaml file. Files are looked up from
+// the chart folder up to the repository root; settings of a file closer to
+// the chart override the ones of its parents.
+type Config struct {
+	// Root stops the lookup of parent configuration files at this file.
+	Root  bool   `yaml:"root,omitempty"`
+	Owner string `yaml:"owner,omitempty"`
+	Admin string `yaml:"admin,omitempty"`
+	Rules struct {
+		Enable  []string `yaml:"enable,omitempty"`
+		Disable []string `yaml:"disable,omitempty"`
+	} `yaml:"rules,omitempty"`
+	Severity map[string]Severity `yaml:"severity,omitempty"`
+	// ReservedWords are folder names rejected in addition to the built-in list.
+	ReservedWords []string `yaml:"reservedWords,omitempty"`
+	// Categories replaces the list of allowed metadata.categories.
+	Categories []string `yaml:"categories,omitempty"`
+	// Values are merged over the fake values used to render the chart.
+	Values map[string]interface{} `yaml:"values,omitempty"`
+}
+
+// LoadConfig reads a single configuration file. Unknown rule IDs and
+// severities are errors, so a typo does not silently leave a rule as it is.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	for _, id := range append(append([]string{}, cfg.Rules.Enable...), cfg.Rules.Disable...) {
+		if _, ok := LookupRule(id); !ok {
+			return nil, fmt.Errorf("unknown rule %s in %s", id, file)
+		}
+	}
+	for id, s := range cfg.Severity {
+		if _, ok := LookupRule(id); !ok {
+			return nil, fmt.Errorf("unknown rule %s in %s", id, file)
+		}
+		if s != SeverityError && s != SeverityWarning && s != SeverityInfo {
+			return nil, fmt.Errorf("invalid severity %q for rule %s in %s", s, id, file)
+		}
+	}
+	return &cfg, nil
+}
+
+// FindConfigFiles returns the configuration files applying to oacPath,
+// ordered from the repository root down to the chart folder. The lookup
+// stops at the first folder containing .git or a file with root: true.
+func FindConfigFiles(oacPath string) ([]string, error) {
+	files, _, err := findConfigs(oacPath)
+	return files, err
+}
+
+// findConfigs is FindConfigFiles also returning the parsed files.
+func findConfigs(oacPath string) ([]string, []*Config, error) {
+	dir, err := filepath.Abs(oacPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []string
+	var cfgs []*Config
+	for {
+		file := filepath.Join(dir, ConfigFileName)
+		if fileExists(file) {
+			cfg, err := LoadConfig(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append([]string{file}, files...)
+			cfgs = append([]*Config{cfg}, cfgs...)
+			if cfg.Root {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if dirExists(filepath.Join(dir, ".git")) || parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return files, cfgs, nil
+}
+
+// LoadConfigForChart finds and merges the configuration files applying to
+// oacPath. It returns an empty Config when there are none.
+func LoadConfigForChart(oacPath string) (*Config, error) {
+	_, cfgs, err := findConfigs(oacPath)
+	if err != nil {
+		return nil, err
+	}
+	merged := &Config{}
+	for _, cfg := range cfgs {
+		merged.merge(cfg)
+	}
+	return merged, nil
+}
+
+func (c *Config) merge(child *Config) {
+	if child.Owner != "" {
+		c.Owner = child.Owner
+	}
+	if child.Admin != "" {
+		c.Admin = child.Admin
+	}
+	for _, id := range child.Rules.Enable {
+		c.Rules.Disable = removeString(c.Rules.Disable, id)
+		c.Rules.Enable = append(removeString(c.Rules.Enable, id), id)
+	}
+	for _, id := range child.Rules.Disable {
+		c.Rules.Enable = removeString(c.Rules.Enable, id)
+		c.Rules.Disable = append(removeString(c.Rules.Disable, id), id)
+	}
+	for id, s := range child.Severity {
+		if c.Severity == nil {
+			c.Severity = make(map[string]Severity)
+		}
+		c.Severity[id] = s
+	}
+	c.ReservedWords = append(c.ReservedWords, child.ReservedWords...)
+	if len(child.Categories) > 0 {
+		c.Categories = child.Categories
+	}
+	if len(child.Values) > 0 {
+		if c.Values == nil {
+			c.Values = make(map[string]interface{})
+		}
+		mergeValues(c.Values, child.Values)
+	}
+}
+
+// Apply copies the settings of c onto o and returns o. A rule listed both
+// as enabled and disabled in the same file ends up disabled.
+func (c *Config) Apply(o *LintOptions) *LintOptions {
+	if c.Owner != "" {
+		o.WithOwner(c.Owner)
+	}
+	if c.Admin != "" {
+		o.WithAdmin(c.Admin)
+	}
+	o.EnableRule(c.Rules.Enable...)
+	o.DisableRule(c.Rules.Disable...)
+	for id, s := range c.Severity {
+		o.WithRuleSeverity(id, s)
+	}
+	o.ReservedWords = append(o.ReservedWords, c.ReservedWords...)
+	if len(c.Categories) > 0 {
+		o.Categories = c.Categories
+	}
+	if len(c.Values) > 0 {
+		if o.Values == nil {
+			o.Values = make(map[string]interface{})
+		}
+		mergeValues(o.Values, c.Values)
+	}
+	return o
+}
+
+// LintOptionsForChart returns DefaultLintOptions with the configuration
+// files applying to oacPath applied.
+func LintOptionsForChart(oacPath string) (*LintOptions, error) {
+	cfg, err := LoadConfigForChart(oacPath)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Apply(DefaultLintOptions()), nil
+}
+
+func removeString(list []string, s string) []string {
+	ret := list[:0:0]
+	for _, v := range list {
+		if v != s {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// mergeValues merges src into dst, values of src win and nested maps are
+// merged recursively.
+func mergeValues(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if sv, ok := v.(map[string]interface{}); ok {
+			if dv, ok := dst[k].(map[string]interface{}); ok {
+				merged := make(map[string]interface{}, len(dv))
+				for dk, dvv := range dv {
+					merged[dk] = dvv
+				}
+				mergeValues(merged, sv)
+				dst[k] = merged
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
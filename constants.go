@@ -11,6 +11,55 @@ const (
 	DaemonSet          = "DaemonSet"
 	ManifestName       = "OlaresManifest.yaml"
 	ManifestRenderKey  = "chart/OlaresManifest.yaml"
+	OwnersFile         = "owners"
+	// control files of the app store workflow, at the root of a chart folder
+	RemoveFile  = ".remove"
+	SuspendFile = ".suspend"
+)
+
+// rule ids attached to findings, stable across releases
+const (
+	RuleManifestLoad       = "OAC-MAN-001"
+	RuleManifestSchema     = "OAC-MAN-002"
+	RuleSupportArch        = "OAC-MAN-003"
+	RuleEntranceName       = "OAC-MAN-004"
+	RuleAppData            = "OAC-MAN-005"
+	RuleCustomValidator    = "OAC-CUS-001"
+	RuleChartRender        = "OAC-RES-001"
+	RuleAppResourceLimit   = "OAC-RES-002"
+	RuleContainerResources = "OAC-RES-003"
+	RuleResourceSum        = "OAC-RES-004"
+	RuleUploadMount        = "OAC-RES-005"
+	RuleDeploymentName     = "OAC-RES-006"
+	RuleResourceNamespace  = "OAC-RES-007"
+	RuleServiceAccountRole = "OAC-RBAC-001"
+	RuleFolderName         = "OAC-FLD-001"
+	RuleFolderExists       = "OAC-FLD-002"
+	RuleChartYaml          = "OAC-FLD-003"
+	RuleChartFields        = "OAC-FLD-004"
+	RuleValuesYaml         = "OAC-FLD-005"
+	RuleTemplatesFolder    = "OAC-FLD-006"
+	RuleManifestFile       = "OAC-FLD-007"
+	RuleNameConsistency    = "OAC-FLD-008"
+	RuleVersionConsistency = "OAC-FLD-009"
+	RuleCategories         = "OAC-FLD-010"
+	RuleReservedFolderName = "OAC-FLD-011"
+	RuleImages             = "OAC-FLD-012"
+	RuleSuppressionUnused  = "OAC-SUP-001"
+	RuleSuppressionReason  = "OAC-SUP-002"
+	RuleLintCanceled       = "OAC-RUN-001"
+	RuleLintPanic          = "OAC-RUN-002"
+	RuleConfigLoad         = "OAC-RUN-003"
+	RulePrMultiDir         = "OAC-PR-001"
+	RulePrFolderMismatch   = "OAC-PR-002"
+	RulePrFolderExists     = "OAC-PR-003"
+	RulePrFolderMissing    = "OAC-PR-004"
+	RulePrControlFiles     = "OAC-PR-005"
+	RulePrOwners           = "OAC-PR-006"
+	RulePrPermission       = "OAC-PR-007"
+	RulePrVersionIncrease  = "OAC-PR-008"
+	RulePrVersionName      = "OAC-PR-009"
+	RulePrUpgradeDesc      = "OAC-PR-010"
 )
 
 const RULES = `rules:
@@ -80,6 +129,8 @@ var (
 	NameMustSame2                = "inconsistent info. name must be the same in chart. name in Chart.yaml:%s, chartFolder:%s, folder in title:%s, OlaresManifest.yaml:%s"
 	VersionMustSame1             = "inconsistent info. Version must be the same in chart. version in OlaresManifest.yaml:%s, Chart.yaml:%s"
 	VersionMustSame2             = "inconsistent info. Version must be the same in chart. version in OlaresManifest.yaml:%s, Chart.yaml:%s, title:%s"
+	VersionNameNeedsBump         = "Invalid change. spec.versionName changed from %s to %s, version %s must be increased too"
+	MissingUpgradeDescription    = "Invalid change. spec.upgradeDescription is required when version changes from %s to %s"
 	InvalidCategories            = "categories %v invalid, must in %v"
 	FolderNameInvalid            = "foldername %s in reserved foldername list, invalid"
 	//chart image invalid
@@ -88,4 +139,10 @@ var (
 	ImageNotFound             = "info not found in image"
 	ImageSizeExceed           = "image size %d exceeds the limit: %d"
 	InvalidImageDimensions    = "invalid image dimensions: %dx%d, should be: %dx%d"
+	//manifest invalid
+	InvalidManifestField     = `"validation failed: %s","msg": "%s"`
+	EmptySupportArch         = "spec.SupportArch can not be empty"
+	UnsupportedArch          = "unsupport arch: %s"
+	DuplicateEntranceName    = "entrances:[%d] name has replicated"
+	MissingAppDataPermission = "found .Values.userspace.appdata in %s, but not set permission.appData in OlaresManifest.yaml"
 )
@@ -1,7 +1,210 @@
 package oachecker
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
 
+// Kinds of chart folder and manifest failures. Errors returned by the checks
+// match one of them with errors.Is; errors.As with a *ChartError gives the
+// details.
+var (
+	ErrInvalidAppCfgType      = errors.New("invalid olaresManifest.type")
+	ErrInvalidFolderName      = errors.New("invalid folder name")
+	ErrFolderNotExist         = errors.New("folder does not exist")
+	ErrMissingChartYaml       = errors.New("missing Chart.yaml")
+	ErrReadChartYaml          = errors.New("failed to read Chart.yaml")
+	ErrParseChartYaml         = errors.New("failed to parse Chart.yaml")
+	ErrAPIVersionFieldEmpty   = errors.New("apiVersion field empty")
+	ErrNameFieldEmpty         = errors.New("name field empty")
+	ErrVersionFieldEmpty      = errors.New("version field empty")
+	ErrMissingValuesYaml      = errors.New("missing values.yaml")
+	ErrMissingTemplatesFolder = errors.New("missing templates folder")
+	ErrMissingAppCfg          = errors.New("missing OlaresManifest.yaml")
+	ErrReadAppCfg             = errors.New("failed to read OlaresManifest.yaml")
+	ErrParseAppCfg            = errors.New("failed to parse OlaresManifest.yaml")
+	ErrNameMismatch           = errors.New("inconsistent name")
+	ErrVersionMismatch        = errors.New("inconsistent version")
+	ErrVersionNotIncreased    = errors.New("version not increased")
+	ErrMissingUpgradeDesc     = errors.New("missing spec.upgradeDescription")
+	ErrInvalidCategories      = errors.New("invalid categories")
+	ErrReservedFolderName     = errors.New("reserved folder name")
+
+	ErrInvalidPromoteImageFormat = errors.New("invalid promote image format")
+	ErrInvalidIconImageFormat    = errors.New("invalid icon image format")
+	ErrImageNotFound             = errors.New("image info not found")
+	ErrImageSizeExceed           = errors.New("image size exceeds the limit")
+	ErrInvalidImageDimensions    = errors.New("invalid image dimensions")
+
+	ErrInvalidManifestField = errors.New("invalid OlaresManifest.yaml field")
+	ErrEmptySupportArch     = errors.New("empty spec.supportArch")
+	ErrUnsupportedArch      = errors.New("unsupported arch")
+	ErrDuplicateEntrance    = errors.New("duplicate entrance name")
+	ErrAppDataPermission    = errors.New("missing permission.appData")
+)
+
+// ErrInvalidTitle is the kind of the *ChartError returned for a PR title not
+// matching [pr type][foldername][version]title.
+var ErrInvalidTitle = errors.New("invalid PR title")
+
+// Failures of a pull request to the app store, see ValidateSubmission. A
+// PR naming a folder missing on the base branch is of kind
+// ErrFolderNotExist.
+var (
+	ErrMultipleFolders = errors.New("changes in multiple folders")
+	ErrFolderMismatch  = errors.New("changed folder differs from the title")
+	ErrFolderExists    = errors.New("folder already exists")
+	ErrControlFiles    = errors.New("invalid control files")
+)
+
+// Failures of the owners file of a chart folder and of the permission checks
+// built on it, see Owners.
+var (
+	ErrNoOwners     = errors.New("no owners")
+	ErrInvalidOwner = errors.New("invalid owner")
+	ErrPermission   = errors.New("permission denied")
+)
+
+// Failures reading a chart archive, see LintArchive.
+var (
+	ErrArchiveInvalid    = errors.New("invalid chart archive")
+	ErrArchiveTooLarge   = errors.New("chart archive exceeds the size limits")
+	ErrArchiveUnsafePath = errors.New("unsafe path in chart archive")
+)
+
+// ChartError is a chart folder or manifest failure. Its message is the one
+// from constants.go; Kind is one of the Err* values above.
+type ChartError struct {
+	Kind error
+	// Folder is the chart folder checked, if known.
+	Folder string
+	// Field names the offending field or file, e.g. Chart.yaml or
+	// Spec.SupportArch[1].
+	Field string
+	// Expected and Actual hold the compared values of mismatch errors.
+	Expected string
+	Actual   string
+	// Err is the underlying cause, e.g. a YAML parse error.
+	Err error
+
+	msg string
+}
+
+func chartErrorf(kind error, folder string, format string, a ...interface{}) *ChartError {
+	return &ChartError{Kind: kind, Folder: folder, msg: fmt.Sprintf(format, a...)}
+}
+
+func (e *ChartError) Error() string {
+	return e.msg
+}
+
+func (e *ChartError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ChartError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ChartError) withField(field string) *ChartError {
+	e.Field = field
+	return e
+}
+
+func (e *ChartError) withValues(expected, actual string) *ChartError {
+	e.Expected = expected
+	e.Actual = actual
+	return e
+}
+
+func (e *ChartError) wrap(err error) *ChartError {
+	e.Err = err
+	return e
+}
+
+// MultiError holds several errors returned together, e.g. every finding of
+// a collect-all lint. errors.Is and errors.As look into each of them.
+type MultiError struct {
+	Errors []error
+}
+
+func (m *MultiError) Error() string {
+	var errStr string
+	for _, e := range m.Errors {
+		errStr += e.Error() + "\n"
+	}
+	return errStr
+}
+
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+// Dedupe drops repeated errors, keeping the first of each. Findings are equal
+// when rule, location, resource and message are; other errors when their
+// messages are.
+func (m *MultiError) Dedupe() *MultiError {
+	seen := make(map[string]bool, len(m.Errors))
+	kept := m.Errors[:0]
+	for _, e := range m.Errors {
+		key := errorKey(e)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, e)
+	}
+	m.Errors = kept
+	return m
+}
+
+// Sort orders the errors by file, line and column, then rule id and message.
+// Errors other than findings sort first.
+func (m *MultiError) Sort() *MultiError {
+	sort.SliceStable(m.Errors, func(i, j int) bool {
+		fi, fj := errorFinding(m.Errors[i]), errorFinding(m.Errors[j])
+		if fi == nil || fj == nil {
+			return fi == nil && fj != nil
+		}
+		if fi.File != fj.File {
+			return fi.File < fj.File
+		}
+		if fi.Line != fj.Line {
+			return fi.Line < fj.Line
+		}
+		if fi.Column != fj.Column {
+			return fi.Column < fj.Column
+		}
+		if fi.RuleID != fj.RuleID {
+			return fi.RuleID < fj.RuleID
+		}
+		return fi.Message < fj.Message
+	})
+	return m
+}
+
+func errorFinding(err error) *Finding {
+	var f *Finding
+	if errors.As(err, &f) {
+		return f
+	}
+	return nil
+}
+
+func errorKey(err error) string {
+	f := errorFinding(err)
+	if f == nil {
+		return err.Error()
+	}
+	return strings.Join([]string{f.RuleID, f.Chart, f.File, f.Path, strconv.Itoa(f.Line),
+		strconv.Itoa(f.Column), f.Kind, f.Name, f.Message}, "\x00")
+}
+
+// AggregateErr returns nil for no errors, the error itself for one and a
+// *MultiError for more.
 func AggregateErr(errs []error) error {
 	switch len(errs) {
 	case 0:
@@ -9,10 +212,6 @@ func AggregateErr(errs []error) error {
 	case 1:
 		return errs[0]
 	default:
-		var errStr string
-		for _, e := range errs {
-			errStr += e.Error() + "\n"
-		}
-		return errors.New(errStr)
+		return &MultiError{Errors: errs}
 	}
 }
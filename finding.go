@@ -0,0 +1,174 @@
+package oachecker
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Severity is the level a Finding is reported with.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Finding is a single problem reported by a check. It implements error so the
+// existing error-returning APIs can hand findings back to callers unchanged.
+type Finding struct {
+	RuleID   string   `json:"ruleId"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Chart is the chart folder name the finding belongs to.
+	Chart string `json:"chart,omitempty"`
+	// File is the path of the offending file relative to the chart folder.
+	File string `json:"file,omitempty"`
+	// Path is the field path inside File, e.g. Entrances[0].Host.
+	Path string `json:"path,omitempty"`
+	// Line and Column locate the finding in File, 1-based, 0 when unknown.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Kind and Name identify the rendered resource for resource findings.
+	Kind string `json:"kind,omitempty"`
+	Name string `json:"name,omitempty"`
+
+	Err error `json:"-"`
+}
+
+func newFinding(ruleID, file string, err error) *Finding {
+	return &Finding{
+		RuleID:   ruleID,
+		Severity: defaultSeverity(ruleID),
+		Message:  err.Error(),
+		File:     file,
+		Err:      err,
+	}
+}
+
+func findingf(ruleID, file string, format string, a ...interface{}) *Finding {
+	return newFinding(ruleID, file, fmt.Errorf(format, a...))
+}
+
+func (f *Finding) withPath(path string) *Finding {
+	f.Path = path
+	return f
+}
+
+func (f *Finding) at(line, column int) *Finding {
+	f.Line = line
+	f.Column = column
+	return f
+}
+
+func (f *Finding) withResource(kind, name string) *Finding {
+	f.Kind = kind
+	f.Name = name
+	return f
+}
+
+func (f *Finding) Error() string {
+	return f.Message
+}
+
+func (f *Finding) Unwrap() error {
+	return f.Err
+}
+
+// asFindings turns an error returned by a check into findings. Errors that
+// already are findings are kept as is, anything else is attributed to ruleID.
+// A *MultiError gives one finding per error it holds.
+func asFindings(err error, ruleID, file string) []*Finding {
+	if err == nil {
+		return nil
+	}
+	var m *MultiError
+	if errors.As(err, &m) {
+		var findings []*Finding
+		for _, e := range m.Errors {
+			findings = append(findings, asFindings(e, ruleID, file)...)
+		}
+		return findings
+	}
+	var f *Finding
+	if errors.As(err, &f) {
+		return []*Finding{f}
+	}
+	return []*Finding{newFinding(ruleID, file, err)}
+}
+
+// findingsErr converts findings back into the error returned by the
+// non-report APIs, nil when there are none. Repeated findings are dropped.
+func findingsErr(findings []*Finding) error {
+	errs := make([]error, 0, len(findings))
+	for _, f := range findings {
+		errs = append(errs, f)
+	}
+	if m, ok := AggregateErr(errs).(*MultiError); ok {
+		return AggregateErr(m.Dedupe().Errors)
+	}
+	return AggregateErr(errs)
+}
+
+// Report holds every finding produced by a lint run on one chart.
+type Report struct {
+	Chart string `json:"chart"`
+	// Path is the chart folder as passed to the lint entry point.
+	Path string `json:"path,omitempty"`
+	// Source is where the chart was fetched from when it was not read from
+	// disk, such as an OCI reference. Path is then the folder in the archive.
+	Source   string     `json:"source,omitempty"`
+	Findings []*Finding `json:"findings"`
+	// Skipped lists the stages that did not run because a stage they depend
+	// on failed. Only collect-all runs get this far.
+	Skipped []SkippedStage `json:"skipped,omitempty"`
+	// Suppressed holds the findings silenced by inline suppression comments.
+	Suppressed []*Finding `json:"suppressed,omitempty"`
+	// Baselined counts the findings hidden because they are in the baseline,
+	// BaselinedFindings holds them so Baseline.Prune still sees them.
+	Baselined         int        `json:"baselined,omitempty"`
+	BaselinedFindings []*Finding `json:"baselinedFindings,omitempty"`
+	// Rules lists the ids of the rules checked: the enabled rules of the
+	// stages that ran.
+	Rules []string `json:"rules,omitempty"`
+}
+
+// SkippedStage records a lint stage that was not run and why.
+type SkippedStage struct {
+	Stage  string `json:"stage"`
+	Reason string `json:"reason"`
+}
+
+func (r *Report) add(findings ...*Finding) {
+	for _, f := range findings {
+		if f.Chart == "" {
+			f.Chart = r.Chart
+		}
+		r.Findings = append(r.Findings, f)
+	}
+}
+
+// HasErrors reports whether the report contains error-level findings.
+func (r *Report) HasErrors() bool {
+	return hasErrors(r.Findings)
+}
+
+func hasErrors(findings []*Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns the error-level findings as a single error, nil if there are none.
+func (r *Report) Err() error {
+	var errs []*Finding
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f)
+		}
+	}
+	return findingsErr(errs)
+}
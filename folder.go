@@ -1,57 +1,54 @@
 package oachecker
 
 import (
-	"fmt"
+	"errors"
+	"io/fs"
 	"os"
-	"path"
-	"path/filepath"
 	"regexp"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
 func baseChartFolderCheck(folder string) (*Chart, *AppConfiguration, string, error) {
-	folderName := path.Base(folder)
+	return bundleFolderCheck(newChartBundle(folder, nil))
+}
+
+// bundleFolderCheck checks the structure of the chart folder of b, reading
+// Chart.yaml and OlaresManifest.yaml through b.
+func bundleFolderCheck(b *ChartBundle) (*Chart, *AppConfiguration, string, error) {
+	folder := b.Path
+	folderName := b.Folder
 	if !isValidFolderName(folderName) {
-		return nil, nil, "", fmt.Errorf(InvalidFolderName, folder)
+		return nil, nil, "", newFinding(RuleFolderName, "", chartErrorf(ErrInvalidFolderName, folder, InvalidFolderName, folder).
+			withValues("^[a-z0-9]{1,30}$", folderName))
 	}
 
-	if !dirExists(folder) {
-		return nil, nil, "", fmt.Errorf(FolderNotExist, folder)
+	if !fsDirExists(b.FS, ".") {
+		return nil, nil, "", newFinding(RuleFolderExists, "", chartErrorf(ErrFolderNotExist, folder, FolderNotExist, folder))
 	}
 
-	chartFile := filepath.Join(folder, "Chart.yaml")
-	if !fileExists(chartFile) {
-		return nil, nil, "", fmt.Errorf(MissingChartYaml, folder)
+	if !fsFileExists(b.FS, "Chart.yaml") {
+		return nil, nil, "", newFinding(RuleChartYaml, "Chart.yaml", chartErrorf(ErrMissingChartYaml, folder, MissingChartYaml, folder).withField("Chart.yaml"))
 	}
 
-	chartContent, err := os.ReadFile(chartFile)
+	chart, err := b.chartYaml()
 	if err != nil {
-		return nil, nil, "", fmt.Errorf(ReadChartYamlFailed, folder, err)
-	}
-	var chart Chart
-	if err := yaml.Unmarshal(chartContent, &chart); err != nil {
-		return nil, nil, "", fmt.Errorf(ParseChartYamlFailed, folder, err)
+		return nil, nil, "", err
 	}
 
-	if err := isValidChartFields(chart); err != nil {
+	if err := isValidChartFields(*chart); err != nil {
 		return nil, nil, "", err
 	}
 
-	valuesFile := filepath.Join(folder, "values.yaml")
-	if !fileExists(valuesFile) {
-		return nil, nil, "", fmt.Errorf(MissingValuesYaml, folder)
+	if !fsFileExists(b.FS, "values.yaml") {
+		return nil, nil, "", newFinding(RuleValuesYaml, "values.yaml", chartErrorf(ErrMissingValuesYaml, folder, MissingValuesYaml, folder).withField("values.yaml"))
 	}
 
-	templatesDir := filepath.Join(folder, "templates")
-	if !dirExists(templatesDir) {
-		return nil, nil, "", fmt.Errorf(MissingTemplatesFolder, folder)
+	if !fsDirExists(b.FS, "templates") {
+		return nil, nil, "", newFinding(RuleTemplatesFolder, "templates", chartErrorf(ErrMissingTemplatesFolder, folder, MissingTemplatesFolder, folder).withField("templates"))
 	}
 
-	appCfgFile := filepath.Join(folder, "OlaresManifest.yaml")
-	if !fileExists(appCfgFile) {
-		return nil, nil, "", fmt.Errorf(MissingAppCfg, folder)
+	if !fsFileExists(b.FS, ManifestName) {
+		return nil, nil, "", newFinding(RuleManifestFile, ManifestName, chartErrorf(ErrMissingAppCfg, folder, MissingAppCfg, folder).withField(ManifestName))
 	}
 
 	//appCfgContent, err := os.ReadFile(appCfgFile)
@@ -63,34 +60,43 @@ func baseChartFolderCheck(folder string) (*Chart, *AppConfiguration, string, err
 	//if err := yaml.Unmarshal(appCfgContent, &appConf); err != nil {
 	//	return nil, nil, "", fmt.Errorf(ParseAppCfgFailed, folder, err)
 	//}
-	appConf, err := GetAppConfiguration(folder)
+	appConf, err := b.appConfiguration()
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return nil, nil, "", newFinding(RuleManifestFile, ManifestName, chartErrorf(ErrReadAppCfg, folder, ReadAppCfgFailed, folder, err).withField(ManifestName).wrap(err))
+	}
 	if err != nil {
-		return nil, nil, "", fmt.Errorf(ReadAppCfgFailed, folder, err)
+		return nil, nil, "", newFinding(RuleManifestFile, ManifestName, chartErrorf(ErrParseAppCfg, folder, ParseAppCfgFailed, folder, err).withField(ManifestName).wrap(err))
 	}
 
-	return &chart, appConf, folderName, nil
+	return chart, appConf, folderName, nil
 }
 
 func CheckChartFolder(folder string) error { // todo extract func
-	_, _, _, err := baseChartFolderCheck(folder)
-	if err != nil {
-		return err
-	}
+	return findingsErr(checkChartFolder(folder))
+}
 
-	return nil
+func checkChartFolder(folder string) []*Finding {
+	_, _, _, err := baseChartFolderCheck(folder)
+	return asFindings(err, RuleChartYaml, "")
 }
 
 func CheckSameVersion(folder string) error {
-	chart, appConf, folderName, err := baseChartFolderCheck(folder)
+	return findingsErr(checkSameVersion(folder))
+}
+
+func checkSameVersion(folder string) []*Finding {
+	return bundleSameVersion(newChartBundle(folder, nil))
+}
+
+func bundleSameVersion(b *ChartBundle) []*Finding {
+	chart, appConf, folderName, err := bundleFolderCheck(b)
 	if err != nil {
-		return err
+		return asFindings(err, RuleChartYaml, "")
 	}
 
 	err = isValidMetadataFields(appConf.Metadata, chart, folderName)
-	if err != nil {
-		return err
-	}
-	return nil
+	return asFindings(err, RuleVersionConsistency, "")
 }
 
 func CheckChartFolderWithTitle(folder string, titleInfo TitleInfo) error {
@@ -103,27 +109,58 @@ func CheckChartFolderWithTitle(folder string, titleInfo TitleInfo) error {
 		return err
 	}
 
-	if !checkCategories(appConf.Metadata.Categories) {
-		return fmt.Errorf(InvalidCategories, appConf.Metadata.Categories, validCategoriesSlice)
-	}
-
-	if checkReservedWord(folderName) {
-		return fmt.Errorf(FolderNameInvalid, folderName)
+	if findings := checkFolderPolicy(appConf, folderName, nil); len(findings) > 0 {
+		return findings[0]
 	}
 
 	if err = CheckAppConfigImages(appConf); err != nil {
-		return err
+		return asFindings(err, RuleImages, ManifestName)[0]
 	}
 
 	return nil
 }
 
-func checkReservedWord(str string) bool {
+// lintChartFolder is the folder stage of Lint, the structure checks of
+// CheckChartFolder plus the category and reserved name rules.
+func lintChartFolder(b *ChartBundle) []*Finding {
+	_, appConf, folderName, err := bundleFolderCheck(b)
+	if err != nil {
+		return asFindings(err, RuleChartYaml, "")
+	}
+	return b.options.applyRules(checkFolderPolicy(appConf, folderName, b.options))
+}
+
+func checkFolderPolicy(appConf *AppConfiguration, folderName string, options *LintOptions) []*Finding {
+	var findings []*Finding
+	categories := validCategoriesSlice
+	var extraReservedWords []string
+	if options != nil {
+		if len(options.Categories) > 0 {
+			categories = options.Categories
+		}
+		extraReservedWords = options.ReservedWords
+	}
+	if !checkCategories(appConf.Metadata.Categories, categories...) {
+		findings = append(findings, newFinding(RuleCategories, ManifestName,
+			chartErrorf(ErrInvalidCategories, folderName, InvalidCategories, appConf.Metadata.Categories, categories).
+				withField("Metadata.Categories").
+				withValues(strings.Join(categories, ","), strings.Join(appConf.Metadata.Categories, ","))).
+			withPath("Metadata.Categories"))
+	}
+
+	if checkReservedWord(folderName, extraReservedWords...) {
+		findings = append(findings, newFinding(RuleReservedFolderName, "", chartErrorf(ErrReservedFolderName, folderName, FolderNameInvalid, folderName)))
+	}
+	return findings
+}
+
+func checkReservedWord(str string, extra ...string) bool {
 	reservedWords := []string{
 		"user", "system", "space", "default", "os", "kubesphere", "kube",
 		"kubekey", "kubernetes", "gpu", "tapr", "bfl", "bytetrade",
 		"project", "pod",
 	}
+	reservedWords = append(reservedWords, extra...)
 
 	for _, word := range reservedWords {
 		if strings.EqualFold(str, word) {
@@ -151,15 +188,18 @@ func dirExists(path string) bool {
 
 func isValidChartFields(chart Chart) error {
 	if chart.APIVersion == "" {
-		return fmt.Errorf(ApiVersionFieldEmptyInAppCfg, chart)
+		return newFinding(RuleChartFields, "Chart.yaml", chartErrorf(ErrAPIVersionFieldEmpty, "", ApiVersionFieldEmptyInAppCfg, chart).withField("apiVersion")).
+			withPath("apiVersion")
 	}
 
 	if chart.Name == "" {
-		return fmt.Errorf(NameFieldEmptyInAppCfg, chart)
+		return newFinding(RuleChartFields, "Chart.yaml", chartErrorf(ErrNameFieldEmpty, "", NameFieldEmptyInAppCfg, chart).withField("name")).
+			withPath("name")
 	}
 
 	if chart.Version == "" {
-		return fmt.Errorf(VersionFieldEmptyInAppCfg, chart)
+		return newFinding(RuleChartFields, "Chart.yaml", chartErrorf(ErrVersionFieldEmpty, "", VersionFieldEmptyInAppCfg, chart).withField("version")).
+			withPath("version")
 	}
 
 	return nil
@@ -167,12 +207,19 @@ func isValidChartFields(chart Chart) error {
 
 func isValidMetadataFieldsWithTitle(metadata AppMetaData, chart *Chart, folder string, titleInfo TitleInfo) error {
 	if chart.Name != folder || titleInfo.Folder != folder || metadata.Name != folder {
-		return fmt.Errorf(NameMustSame2,
-			chart.Name, folder, titleInfo.Folder, metadata.Name)
+		return newFinding(RuleNameConsistency, ManifestName,
+			chartErrorf(ErrNameMismatch, folder, NameMustSame2, chart.Name, folder, titleInfo.Folder, metadata.Name).
+				withField("Metadata.Name").
+				withValues(folder, firstDifferent(folder, chart.Name, titleInfo.Folder, metadata.Name))).
+			withPath("Metadata.Name")
 	}
 
 	if metadata.Version != chart.Version || titleInfo.Version != chart.Version {
-		return fmt.Errorf(VersionMustSame2, metadata.Version, chart.Version, titleInfo.Version)
+		return newFinding(RuleVersionConsistency, ManifestName,
+			chartErrorf(ErrVersionMismatch, folder, VersionMustSame2, metadata.Version, chart.Version, titleInfo.Version).
+				withField("Metadata.Version").
+				withValues(chart.Version, firstDifferent(chart.Version, metadata.Version, titleInfo.Version))).
+			withPath("Metadata.Version")
 	}
 
 	return nil
@@ -180,13 +227,30 @@ func isValidMetadataFieldsWithTitle(metadata AppMetaData, chart *Chart, folder s
 
 func isValidMetadataFields(metadata AppMetaData, chart *Chart, folder string) error {
 	if chart.Name != folder || metadata.Name != folder {
-		return fmt.Errorf(NameMustSame1,
-			chart.Name, folder, metadata.Name)
+		return newFinding(RuleNameConsistency, ManifestName,
+			chartErrorf(ErrNameMismatch, folder, NameMustSame1, chart.Name, folder, metadata.Name).
+				withField("Metadata.Name").
+				withValues(folder, firstDifferent(folder, chart.Name, metadata.Name))).
+			withPath("Metadata.Name")
 	}
 
 	if metadata.Version != chart.Version {
-		return fmt.Errorf(VersionMustSame1, metadata.Version, chart.Version)
+		return newFinding(RuleVersionConsistency, ManifestName,
+			chartErrorf(ErrVersionMismatch, folder, VersionMustSame1, metadata.Version, chart.Version).
+				withField("Metadata.Version").
+				withValues(chart.Version, metadata.Version)).
+			withPath("Metadata.Version")
 	}
 
 	return nil
 }
+
+// firstDifferent returns the first of values not equal to expected.
+func firstDifferent(expected string, values ...string) string {
+	for _, v := range values {
+		if v != expected {
+			return v
+		}
+	}
+	return expected
+}
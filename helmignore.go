@@ -0,0 +1,102 @@
+package oachecker
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// helmIgnoreFile is the file listing the paths Helm leaves out of a chart.
+const helmIgnoreFile = ".helmignore"
+
+// helmIgnore are the rules of a .helmignore file. Helm keeps its parser
+// internal, so this follows helm.sh/helm/v3/internal/ignore, including how
+// negated rules are evaluated.
+type helmIgnore struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	rule string
+	// base rules have no slash and match the last element only
+	base    bool
+	negate  bool
+	mustDir bool
+}
+
+// parseHelmIgnore parses r and adds Helm's default rule ignoring dot files in
+// templates/.
+func parseHelmIgnore(r io.Reader) (*helmIgnore, error) {
+	ig := &helmIgnore{}
+	s := bufio.NewScanner(r)
+	for first := true; s.Scan(); first = false {
+		line := s.Bytes()
+		if first {
+			line = bytes.TrimPrefix(line, utf8bom)
+		}
+		if err := ig.add(string(line)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return ig, ig.add(`templates/.?*`)
+}
+
+func (ig *helmIgnore) add(rule string) error {
+	rule = strings.TrimSpace(rule)
+	if rule == "" || strings.HasPrefix(rule, "#") {
+		return nil
+	}
+	if strings.Contains(rule, "**") {
+		return errors.New("double-star (**) syntax is not supported")
+	}
+	if _, err := path.Match(rule, "abc"); err != nil {
+		return err
+	}
+	var p ignorePattern
+	if strings.HasPrefix(rule, "!") {
+		p.negate = true
+		rule = rule[1:]
+	}
+	if strings.HasSuffix(rule, "/") {
+		p.mustDir = true
+		rule = strings.TrimSuffix(rule, "/")
+	}
+	p.base = !strings.Contains(rule, "/")
+	p.rule = strings.TrimPrefix(rule, "/")
+	ig.patterns = append(ig.patterns, p)
+	return nil
+}
+
+// ignore reports whether the file or folder name, relative to the chart
+// folder, is left out of the chart.
+func (ig *helmIgnore) ignore(name string, isDir bool) bool {
+	if name == "" || name == "." || name == "./" {
+		return false
+	}
+	for _, p := range ig.patterns {
+		n := name
+		if p.base {
+			n = path.Base(name)
+		}
+		ok, _ := path.Match(p.rule, n)
+		if p.negate {
+			if (p.mustDir && !isDir) || !ok {
+				return true
+			}
+			continue
+		}
+		if p.mustDir && !isDir {
+			continue
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
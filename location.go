@@ -0,0 +1,201 @@
+package oachecker
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestLocator maps field paths of AppConfiguration, as reported by the
+// validator (e.g. Entrances[0].Host), onto positions in OlaresManifest.yaml
+// as written, before Helm templating.
+type manifestLocator struct {
+	root *yaml.Node
+	// source holds the lines of the file as written
+	source []string
+	// lineMap maps a 0-based line of the rendered manifest onto the 1-based
+	// line of the source it came from.
+	lineMap []int
+}
+
+func newManifestLocator(raw []byte, rendered string) *manifestLocator {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(rendered), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	source := strings.Split(string(raw), "\n")
+	return &manifestLocator{
+		root:    doc.Content[0],
+		source:  source,
+		lineMap: alignLines(source, strings.Split(rendered, "\n")),
+	}
+}
+
+// alignLines matches every rendered line with the source line it was
+// rendered from. Templating only drops lines ({{- if }}, {{- end }}) or
+// rewrites values in place, so lines are matched in order: first by equal
+// content, then by equal key for lines whose value was templated.
+func alignLines(source, rendered []string) []int {
+	lineMap := make([]int, len(rendered))
+	cursor := 0
+	for i, line := range rendered {
+		j := findLine(source, cursor, func(s string) bool { return s == line })
+		if j < 0 {
+			key := lineKey(line)
+			j = findLine(source, cursor, func(s string) bool {
+				return key != "" && lineKey(s) == key && strings.Contains(s, "{{")
+			})
+		}
+		if j < 0 {
+			lineMap[i] = cursor + 1
+			continue
+		}
+		lineMap[i] = j + 1
+		cursor = j + 1
+	}
+	return lineMap
+}
+
+func findLine(lines []string, from int, match func(string) bool) int {
+	for j := from; j < len(lines); j++ {
+		if match(lines[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// lineKey returns the indentation and key of a "key: value" line.
+func lineKey(line string) string {
+	idx := strings.Index(line, ":")
+	if idx < 0 {
+		return ""
+	}
+	return line[:idx]
+}
+
+// locate returns the 1-based source line and column of fieldPath. Fields
+// missing from the file resolve to their closest present parent.
+func (m *manifestLocator) locate(fieldPath string) (int, int) {
+	if m == nil {
+		return 0, 0
+	}
+	keys := yamlPath(reflect.TypeOf(AppConfiguration{}), fieldPath)
+	node, key := m.root, m.root
+	for _, k := range keys {
+		child, childKey := lookupNode(node, k)
+		if child == nil {
+			break
+		}
+		node, key = child, childKey
+	}
+	if key == m.root {
+		return 1, 1
+	}
+	return m.position(key)
+}
+
+// position translates a node position in the rendered manifest to the
+// source file.
+func (m *manifestLocator) position(n *yaml.Node) (int, int) {
+	if n.Line-1 >= len(m.lineMap) {
+		return n.Line, n.Column
+	}
+	line := m.lineMap[n.Line-1]
+	column := n.Column
+	if line-1 < len(m.source) && n.Kind == yaml.ScalarNode {
+		if idx := strings.Index(m.source[line-1], n.Value); idx >= 0 {
+			column = idx + 1
+		}
+	}
+	return line, column
+}
+
+// lookupNode returns the value node for key k in a mapping, or the item at
+// index k of a sequence, together with the node to report: the key node for
+// mappings, the item for sequences.
+func lookupNode(n *yaml.Node, k string) (*yaml.Node, *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == k {
+				return n.Content[i+1], n.Content[i]
+			}
+		}
+	case yaml.SequenceNode:
+		idx, err := strconv.Atoi(k)
+		if err == nil && idx >= 0 && idx < len(n.Content) {
+			return n.Content[idx], n.Content[idx]
+		}
+	}
+	return nil, nil
+}
+
+// yamlPath converts a Go field path like Options.Dependencies[1].Type into
+// the YAML keys options, dependencies, 1, type using the yaml tags of t.
+func yamlPath(t reflect.Type, fieldPath string) []string {
+	var keys []string
+	for _, segment := range strings.Split(fieldPath, ".") {
+		name := segment
+		var indexes []string
+		if idx := strings.Index(segment, "["); idx >= 0 {
+			name = segment[:idx]
+			for _, part := range strings.Split(segment[idx+1:], "[") {
+				indexes = append(indexes, strings.TrimSuffix(part, "]"))
+			}
+		}
+		t = derefType(t)
+		if t.Kind() != reflect.Struct {
+			keys = append(keys, name)
+			keys = append(keys, indexes...)
+			continue
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			keys = append(keys, name)
+			keys = append(keys, indexes...)
+			continue
+		}
+		keys = append(keys, yamlName(field))
+		keys = append(keys, indexes...)
+		t = field.Type
+		for range indexes {
+			t = derefType(t)
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+				t = t.Elem()
+			}
+		}
+	}
+	return keys
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func yamlName(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if tag == "" || tag == "-" {
+		return strings.ToLower(field.Name)
+	}
+	return tag
+}
+
+// annotate sets the line and column of manifest findings carrying a field
+// path but no position yet.
+func (m *manifestLocator) annotate(findings []*Finding) {
+	if m == nil {
+		return
+	}
+	for _, f := range findings {
+		if f.File != ManifestName || f.Path == "" || f.Line != 0 {
+			continue
+		}
+		f.Line, f.Column = m.locate(f.Path)
+	}
+}
@@ -3,7 +3,7 @@ package oachecker
 import (
 	"bufio"
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
 	vd "github.com/bytedance/go-tagexpr/v2/validator"
 	"gopkg.in/yaml.v3"
@@ -11,11 +11,13 @@ import (
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/engine"
 	"io"
+	"io/fs"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"os"
-	"path/filepath"
+	"path"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 const (
@@ -30,15 +32,22 @@ func getAppConfigFromCfg(f io.ReadCloser, opts ...func(map[string]interface{}))
 	}
 	defer f.Close()
 
-	renderedData, err := RenderManifestFromContent(data, opts...)
+	cfg, _, err := loadManifest(data, opts...)
+	return cfg, err
+}
+
+// loadManifest renders and parses OlaresManifest.yaml content and returns a
+// locator mapping field paths of the result back onto content.
+func loadManifest(content []byte, opts ...func(map[string]interface{})) (*AppConfiguration, *manifestLocator, error) {
+	renderedData, err := RenderManifestFromContent(content, opts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	var cfg AppConfiguration
 	if err := yaml.Unmarshal([]byte(renderedData), &cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return &cfg, nil
+	return &cfg, newManifestLocator(content, renderedData), nil
 }
 
 //func getAppConfigFromCfgFile(oacPath string, owner, admin string) (*AppConfiguration, error) {
@@ -53,52 +62,110 @@ func getAppConfigFromCfg(f io.ReadCloser, opts ...func(map[string]interface{}))
 //}
 
 func CheckAppCfg(oacPath string, opts ...func(map[string]interface{})) error {
-	cfg, err := GetAppConfiguration(oacPath, opts...)
-	if err != nil {
-		return err
-	}
-	return checkAppCfg(cfg, oacPath, true)
+	return CheckAppCfgContext(context.Background(), oacPath, opts...)
+}
+
+func CheckAppCfgContext(ctx context.Context, oacPath string, opts ...func(map[string]interface{})) error {
+	b := newChartBundle(oacPath, nil)
+	b.manifestOpts = opts
+	return findingsErr(checkAppCfg(ctx, b, true))
 }
 
-func checkAppCfg(cfg *AppConfiguration, oacPath string, checkAll ...bool) error {
-	err := vd.Validate(cfg, checkAll...)
+func checkAppCfg(ctx context.Context, b *ChartBundle, checkAll ...bool) []*Finding {
+	cfg, err := b.appConfiguration()
 	if err != nil {
-		return err
+		return []*Finding{newFinding(RuleManifestLoad, ManifestName, err)}
+	}
+	findings := validateManifest(cfg, checkAll...)
+	if len(findings) > 0 {
+		return findings
 	}
 	//err = CheckAppEntrances(cfg)
 	//if err != nil {
 	//	return err
 	//}
-	err = CheckSupportedArch(cfg)
-	if err != nil {
-		return err
+	findings = checkSupportedArch(cfg)
+	if len(findings) > 0 {
+		return findings
 	}
 
-	err = CheckAppData(oacPath, cfg)
-	if err != nil {
-		return err
+	findings = checkAppData(ctx, b.FS, cfg)
+	if len(findings) > 0 {
+		return findings
+	}
+	return b.resourceFindings(ctx)
+}
+
+// manifestValidators pools validators of the vd tag rules of
+// AppConfiguration, as a validator caches the expressions compiled from the
+// tags. Each validator collects the findings of its own run, so concurrent
+// lint runs do not share state.
+var manifestValidators = sync.Pool{New: func() interface{} { return newManifestValidator() }}
+
+type manifestValidator struct {
+	v        *vd.Validator
+	findings []*Finding
+}
+
+func newManifestValidator() *manifestValidator {
+	m := &manifestValidator{}
+	m.v = vd.New("vd").SetErrorFactory(func(failPath, msg string) error {
+		f := newFinding(RuleManifestSchema, ManifestName,
+			chartErrorf(ErrInvalidManifestField, "", InvalidManifestField, failPath, msg).withField(failPath)).
+			withPath(failPath)
+		m.findings = append(m.findings, f)
+		return f
+	})
+	return m
+}
+
+// validateManifest runs the vd tag rules of AppConfiguration and reports one
+// finding per failing field.
+func validateManifest(cfg *AppConfiguration, checkAll ...bool) []*Finding {
+	m := manifestValidators.Get().(*manifestValidator)
+	defer manifestValidators.Put(m)
+	m.findings = nil
+	err := m.v.Validate(cfg, checkAll...)
+	findings := m.findings
+	m.findings = nil
+	if err != nil && len(findings) == 0 {
+		findings = append(findings, newFinding(RuleManifestSchema, ManifestName, err))
 	}
-	return CheckResource(oacPath, cfg, nil)
+	return findings
 }
 
 func CheckSupportedArch(cfg *AppConfiguration) error {
+	return findingsErr(checkSupportedArch(cfg))
+}
+
+func checkSupportedArch(cfg *AppConfiguration) []*Finding {
 	if len(cfg.Spec.SupportArch) == 0 {
-		return errors.New("spec.SupportArch can not be empty")
+		return []*Finding{newFinding(RuleSupportArch, ManifestName,
+			chartErrorf(ErrEmptySupportArch, "", EmptySupportArch).withField("Spec.SupportArch")).withPath("Spec.SupportArch")}
 	}
 	allSupportedArch := sets.String{"amd64": sets.Empty{}, "arm32v5": sets.Empty{}, "arm32v6": sets.Empty{},
 		"arm32v7": sets.Empty{}, "arm64v8": sets.Empty{}, "i386": sets.Empty{}, "ppc64le": sets.Empty{},
 		"s390x": sets.Empty{}, "mips64le": sets.Empty{}, "riscv64": sets.Empty{}, "windows-amd64": sets.Empty{}, "arm64": sets.Empty{}}
-	for _, arch := range cfg.Spec.SupportArch {
+	var findings []*Finding
+	for i, arch := range cfg.Spec.SupportArch {
 		if !allSupportedArch.Has(arch) {
-			return fmt.Errorf("unsupport arch: %s", arch)
+			path := fmt.Sprintf("Spec.SupportArch[%d]", i)
+			findings = append(findings, newFinding(RuleSupportArch, ManifestName,
+				chartErrorf(ErrUnsupportedArch, "", UnsupportedArch, arch).withField(path).withValues("", arch)).
+				withPath(path))
 		}
 	}
-	return nil
+	return findings
 }
 
 func CheckAppEntrances(cfg *AppConfiguration) error {
+	return findingsErr(checkAppEntrances(cfg))
+}
+
+func checkAppEntrances(cfg *AppConfiguration) []*Finding {
 	//setsEntrance := sets.String{}
 	setsName := sets.String{}
+	var findings []*Finding
 	for i, e := range cfg.Entrances {
 		//entrance := fmt.Sprintf("%s:%d", e.Host, e.Port)
 		//if setsEntrance.Has(entrance) {
@@ -107,29 +174,50 @@ func CheckAppEntrances(cfg *AppConfiguration) error {
 		//setsEntrance.Insert(entrance)
 
 		if setsName.Has(e.Name) {
-			return fmt.Errorf("entrances:[%d] name has replicated", i)
+			path := fmt.Sprintf("Entrances[%d].Name", i)
+			findings = append(findings, newFinding(RuleEntranceName, ManifestName,
+				chartErrorf(ErrDuplicateEntrance, "", DuplicateEntranceName, i).withField(path).withValues("", e.Name)).
+				withPath(path))
 		}
 		setsName.Insert(e.Name)
 	}
-	return nil
+	return findings
 }
 
 func CheckAppData(oacPath string, cfg *AppConfiguration) error {
+	return CheckAppDataContext(context.Background(), oacPath, cfg)
+}
+
+// CheckAppDataContext is CheckAppData, stopping the walk over the templates
+// once ctx is done. It can be passed to WithContextValidator.
+func CheckAppDataContext(ctx context.Context, oacPath string, cfg *AppConfiguration) error {
+	return findingsErr(checkAppData(ctx, os.DirFS(oacPath), cfg))
+}
+
+// CheckAppDataFS is CheckAppDataContext for a chart folder at the root of
+// fsys.
+func CheckAppDataFS(ctx context.Context, fsys fs.FS, cfg *AppConfiguration) error {
+	return findingsErr(checkAppData(ctx, fsys, cfg))
+}
+
+func checkAppData(ctx context.Context, fsys fs.FS, cfg *AppConfiguration) []*Finding {
 	if cfg.Permission.AppData {
 		return nil
 	}
-	if !strings.HasSuffix(oacPath, "/") {
-		oacPath += "/"
-	}
-	oacPath += "templates"
 	p, err := regexp.Compile(`\.Values\.userspace\.appdata`)
 	if err != nil {
-		return err
+		return asFindings(err, RuleAppData, "")
 	}
-	var rerr error
-	err = filepath.Walk(oacPath, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && strings.HasSuffix(path, ".yaml") {
-			f, e := os.Open(path)
+	var findings []*Finding
+	err = fs.WalkDir(fsys, "templates", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(name, ".yaml") {
+			f, e := fsys.Open(name)
 			if e != nil {
 				return e
 			}
@@ -137,16 +225,22 @@ func CheckAppData(oacPath string, cfg *AppConfiguration) error {
 			scanner := bufio.NewScanner(f)
 			for scanner.Scan() {
 				if p.MatchString(scanner.Text()) {
-					rerr = fmt.Errorf("found .Values.userspace.appdata in %s, but not set permission.appData in OlaresManifest.yaml", filepath.Base(path))
+					findings = append(findings, newFinding(RuleAppData, name,
+						chartErrorf(ErrAppDataPermission, "", MissingAppDataPermission, path.Base(name)).withField("Permission.AppData")).
+						withPath("Permission.AppData"))
+					break
 				}
 			}
 		}
 		return nil
 	})
 	if err != nil {
-		return err
+		if f := contextFinding(ctx); f != nil {
+			return []*Finding{f}
+		}
+		return asFindings(err, RuleAppData, "")
 	}
-	return rerr
+	return findings
 }
 
 func RenderManifestFromContent(content []byte, opts ...func(map[string]interface{})) (string, error) {
@@ -209,14 +303,16 @@ func WithAdmin(admin string) func(map[string]interface{}) {
 }
 
 func GetAppConfiguration(oacPath string, opts ...func(map[string]interface{})) (*AppConfiguration, error) {
-	if !strings.HasSuffix(oacPath, "/") {
-		oacPath += "/"
-	}
-	f, err := os.Open(oacPath + ManifestName)
+	return GetAppConfigurationFS(os.DirFS(oacPath), opts...)
+}
+
+// GetAppConfigurationFS is GetAppConfiguration for a chart folder at the root
+// of fsys.
+func GetAppConfigurationFS(fsys fs.FS, opts ...func(map[string]interface{})) (*AppConfiguration, error) {
+	f, err := fsys.Open(ManifestName)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 	return getAppConfigFromCfg(f, opts...)
 }
 
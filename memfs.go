@@ -0,0 +1,116 @@
+package oachecker
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// memFS is a read-only file system over file contents keyed by slash
+// separated path. Folders are derived from the file paths.
+type memFS struct {
+	files map[string][]byte
+	dirs  map[string][]string
+}
+
+func newMemFS(files map[string][]byte) *memFS {
+	children := map[string]map[string]bool{".": {}}
+	for name := range files {
+		for child := name; child != "."; child = path.Dir(child) {
+			parent := path.Dir(child)
+			if children[parent] == nil {
+				children[parent] = make(map[string]bool)
+			}
+			children[parent][path.Base(child)] = true
+		}
+	}
+	m := &memFS{files: files, dirs: make(map[string][]string, len(children))}
+	for dir, set := range children {
+		names := make([]string, 0, len(set))
+		for name := range set {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		m.dirs[dir] = names
+	}
+	return m
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := m.files[name]; ok {
+		return &memFile{info: m.info(name), r: bytes.NewReader(data)}, nil
+	}
+	if _, ok := m.dirs[name]; ok {
+		return &memDir{fs: m, name: name}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *memFS) info(name string) *memInfo {
+	if data, ok := m.files[name]; ok {
+		return &memInfo{name: path.Base(name), size: int64(len(data)), mode: 0444}
+	}
+	return &memInfo{name: path.Base(name), mode: fs.ModeDir | 0555}
+}
+
+// memInfo is both the fs.FileInfo and the fs.DirEntry of a memFS entry.
+type memInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (i *memInfo) Name() string               { return i.name }
+func (i *memInfo) Size() int64                { return i.size }
+func (i *memInfo) Mode() fs.FileMode          { return i.mode }
+func (i *memInfo) ModTime() time.Time         { return time.Time{} }
+func (i *memInfo) IsDir() bool                { return i.mode.IsDir() }
+func (i *memInfo) Sys() interface{}           { return nil }
+func (i *memInfo) Type() fs.FileMode          { return i.mode.Type() }
+func (i *memInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+type memFile struct {
+	info *memInfo
+	r    *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error)                   { return f.info, nil }
+func (f *memFile) Read(p []byte) (int, error)                   { return f.r.Read(p) }
+func (f *memFile) Seek(offset int64, whence int) (int64, error) { return f.r.Seek(offset, whence) }
+func (f *memFile) ReadAt(p []byte, off int64) (int, error)      { return f.r.ReadAt(p, off) }
+func (f *memFile) Close() error                                 { return nil }
+
+type memDir struct {
+	fs     *memFS
+	name   string
+	offset int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.fs.info(d.name), nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	names := d.fs.dirs[d.name][d.offset:]
+	if n > 0 && len(names) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && len(names) > n {
+		names = names[:n]
+	}
+	d.offset += len(names)
+	entries := make([]fs.DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = d.fs.info(path.Join(d.name, name))
+	}
+	return entries, nil
+}
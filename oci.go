@@ -0,0 +1,125 @@
+package oachecker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// OCIOptions configure pulling a chart from an OCI registry.
+type OCIOptions struct {
+	// PlainHTTP talks HTTP instead of HTTPS, for local registries.
+	PlainHTTP bool
+	// InsecureSkipTLSVerify accepts any certificate of the registry.
+	InsecureSkipTLSVerify bool
+	// CredentialsFile is the registry login file, Helm's when empty.
+	CredentialsFile string
+}
+
+// newRegistryClient returns a Helm registry client whose requests are bound
+// to ctx and whose responses are cut off past maxBytes.
+func newRegistryClient(ctx context.Context, options *OCIOptions, maxBytes int64) (*registry.Client, error) {
+	if options == nil {
+		options = &OCIOptions{}
+	}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if options.InsecureSkipTLSVerify {
+		base.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	opts := []registry.ClientOption{
+		registry.ClientOptHTTPClient(&http.Client{
+			Transport: &registryTransport{ctx: ctx, base: base, maxBytes: maxBytes},
+		}),
+	}
+	if options.PlainHTTP {
+		opts = append(opts, registry.ClientOptPlainHTTP())
+	}
+	if options.CredentialsFile != "" {
+		opts = append(opts, registry.ClientOptCredentialsFile(options.CredentialsFile))
+	}
+	return registry.NewClient(opts...)
+}
+
+// registryTransport binds the requests of a registry client to ctx, as Helm's
+// Pull takes no context, and bounds the size of every response.
+type registryTransport struct {
+	ctx      context.Context
+	base     http.RoundTripper
+	maxBytes int64
+}
+
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req.WithContext(t.ctx))
+	if err != nil {
+		return nil, err
+	}
+	if t.maxBytes > 0 {
+		if resp.ContentLength > t.maxBytes {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %s has %d bytes", ErrArchiveTooLarge, req.URL, resp.ContentLength)
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, left: t.maxBytes, url: req.URL.String()}
+	}
+	return resp, nil
+}
+
+// limitedBody fails a response body growing past its limit instead of
+// truncating it silently.
+type limitedBody struct {
+	io.ReadCloser
+	left int64
+	url  string
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	if b.left < 0 {
+		return n, fmt.Errorf("%w: %s", ErrArchiveTooLarge, b.url)
+	}
+	return n, err
+}
+
+// PullChart downloads the chart archive ref, e.g.
+// oci://registry.example.com/charts/firefox:1.0.1, into memory. The archive
+// may be at most DefaultArchiveLimits.MaxTotalSize bytes.
+func PullChart(ctx context.Context, ref string, options *OCIOptions) ([]byte, error) {
+	if !registry.IsOCI(ref) {
+		return nil, fmt.Errorf("%s is not an OCI reference, expected %s://host/repository:tag", ref, registry.OCIScheme)
+	}
+	client, err := newRegistryClient(ctx, options, DefaultArchiveLimits.MaxTotalSize)
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.Pull(strings.TrimPrefix(ref, registry.OCIScheme+"://"))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	return result.Chart.Data, nil
+}
+
+// LintOCI pulls the chart ref from an OCI registry and lints it like
+// LintArchiveContext, without writing it to disk. The report source is ref.
+func LintOCI(ctx context.Context, ref string, oci *OCIOptions, options *LintOptions) (*Report, error) {
+	data, err := PullChart(ctx, ref, oci)
+	if err != nil {
+		return nil, err
+	}
+	report, err := LintArchiveContext(ctx, bytes.NewReader(data), options)
+	if report != nil {
+		report.Source = ref
+	}
+	return report, err
+}
@@ -0,0 +1,96 @@
+package oachecker
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Owners are the GitHub logins allowed to change a chart folder, as listed
+// in its owners file:
+//
+//	owners:
+//	- 'alice'
+//	- 'bob'
+type Owners []string
+
+var githubLoginRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,37}[A-Za-z0-9])?$`)
+
+// ParseOwners parses the content of an owners file. It does not validate
+// the logins, see Owners.Validate.
+func ParseOwners(data []byte) (Owners, error) {
+	var file struct {
+		Owners Owners `yaml:"owners"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Owners, nil
+}
+
+// LoadOwners reads and parses the owners file of the chart folder dir of
+// fsys. The error matches fs.ErrNotExist when the file is missing.
+func LoadOwners(fsys fs.FS, dir string) (Owners, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, OwnersFile))
+	if err != nil {
+		return nil, err
+	}
+	owners, err := ParseOwners(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path.Join(dir, OwnersFile), err)
+	}
+	return owners, nil
+}
+
+// Validate checks that o lists at least one owner, that every owner is a
+// well-formed GitHub login and that no login is listed twice, ignoring
+// case as GitHub does. The error is a *ChartError of kind ErrNoOwners with
+// the FolderNoOwners message or of kind ErrInvalidOwner with the
+// PrOwnerInvalid message and the login as Actual.
+func (o Owners) Validate(folder string) error {
+	if len(o) == 0 {
+		return chartErrorf(ErrNoOwners, folder, FolderNoOwners, folder).withField(OwnersFile)
+	}
+	seen := make(map[string]bool, len(o))
+	for i, login := range o {
+		field := fmt.Sprintf("Owners[%d]", i)
+		if !githubLoginRe.MatchString(login) || strings.Contains(login, "--") {
+			return chartErrorf(ErrInvalidOwner, folder, PrOwnerInvalid, login).withField(field).withValues("", login)
+		}
+		key := strings.ToLower(login)
+		if seen[key] {
+			return chartErrorf(ErrInvalidOwner, folder, PrOwnerInvalid, login).withField(field).withValues("", login).
+				wrap(fmt.Errorf("duplicate owner %q", login))
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Contains reports whether login is one of o, ignoring case.
+func (o Owners) Contains(login string) bool {
+	for _, owner := range o {
+		if strings.EqualFold(owner, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks that actor may change the chart folder owned by o: o
+// must be valid and list actor. Besides the errors of Validate, the error is
+// a *ChartError of kind ErrPermission with the PrPermission message and the
+// actor as Actual.
+func (o Owners) Authorize(folder, actor string) error {
+	if err := o.Validate(folder); err != nil {
+		return err
+	}
+	if actor == "" || !o.Contains(actor) {
+		return chartErrorf(ErrPermission, folder, PrPermission, folder).withValues("", actor)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package oachecker
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// PR types of the app store workflow, the first part of a PR title.
+const (
+	PrTypeNew     = "NEW"
+	PrTypeUpdate  = "UPDATE"
+	PrTypeRemove  = "REMOVE"
+	PrTypeSuspend = "SUSPEND"
+)
+
+// PrTypes lists the PR types accepted by ParsePRTitle.
+var PrTypes = []string{PrTypeNew, PrTypeUpdate, PrTypeRemove, PrTypeSuspend}
+
+var prTitleRe = regexp.MustCompile(`^\s*\[([^\[\]]*)\]\s*\[([^\[\]]*)\]\s*\[([^\[\]]*)\]\s*(.*?)\s*$`)
+
+// ParsePRTitle parses a PR title of the form [pr type][foldername][version]title.
+// Whitespace around and inside the brackets is ignored and the PR type is
+// matched case-insensitively and returned upper-cased. The version must be
+// a semantic version as accepted by Helm for Chart.yaml.
+//
+// The error is a *ChartError of kind ErrInvalidTitle with the TitleInvalid
+// message; its Field and Actual tell the part that is wrong.
+func ParsePRTitle(title string) (TitleInfo, error) {
+	m := prTitleRe.FindStringSubmatch(title)
+	if m == nil {
+		return TitleInfo{}, titleError("", title, fmt.Errorf("title %q does not start with three bracketed parts", title))
+	}
+	info := TitleInfo{
+		PrType:  strings.ToUpper(strings.TrimSpace(m[1])),
+		Folder:  strings.TrimSpace(m[2]),
+		Version: strings.TrimSpace(m[3]),
+		Title:   m[4],
+	}
+	if !isPrType(info.PrType) {
+		return info, titleError("PrType", info.PrType, fmt.Errorf("unknown pr type %q, must in %v", info.PrType, PrTypes))
+	}
+	if !isValidFolderName(info.Folder) {
+		return info, titleError("Folder", info.Folder, fmt.Errorf(InvalidFolderName, info.Folder))
+	}
+	if _, err := semver.NewVersion(info.Version); err != nil {
+		return info, titleError("Version", info.Version, fmt.Errorf("invalid version %q: %w", info.Version, err))
+	}
+	return info, nil
+}
+
+func isPrType(prType string) bool {
+	for _, t := range PrTypes {
+		if prType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func titleError(field, actual string, err error) *ChartError {
+	return chartErrorf(ErrInvalidTitle, "", TitleInvalid).withField(field).withValues("", actual).wrap(err)
+}
+
+// Submission is a pull request to the app store repository, one folder per
+// chart at its root.
+type Submission struct {
+	// Title is the parsed PR title, see ParsePRTitle.
+	Title TitleInfo
+	// Changed lists the paths changed by the PR, slash separated and
+	// relative to the repository root.
+	Changed []string
+	// Base is the repository at the base branch.
+	Base fs.FS
+	// Head is the repository at the head of the PR. It is only read to
+	// validate a changed owners file and may be nil otherwise.
+	Head fs.FS
+	// Actor is the GitHub login of the PR author.
+	Actor string
+}
+
+// ValidateSubmission checks the change list of s against the app store
+// workflow: a PR changes a single folder, the one named in its title; a NEW
+// PR adds a folder missing from the base branch while the other types change
+// an existing one; REMOVE PRs only add the .remove control file, SUSPEND PRs
+// add .suspend and no other control file and NEW and UPDATE PRs none at all.
+// The owners file of an UPDATE PR may only be changed by an owner listed in
+// the base branch and must stay valid in Head.
+// Checking stops at the first folder problem as the rest relies on it.
+// The findings wrap a *ChartError of kind ErrMultipleFolders,
+// ErrFolderMismatch, ErrFolderExists, ErrFolderNotExist or ErrControlFiles,
+// or the errors of the owners checks.
+func ValidateSubmission(s *Submission) []*Finding {
+	var changed []string
+	dirs := map[string]bool{}
+	for _, p := range s.Changed {
+		p = strings.TrimPrefix(path.Clean("/"+p), "/")
+		if p == "" {
+			continue
+		}
+		changed = append(changed, p)
+		dirs[strings.SplitN(p, "/", 2)[0]] = true
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	if len(dirs) > 1 {
+		names := make([]string, 0, len(dirs))
+		for d := range dirs {
+			names = append(names, d)
+		}
+		sort.Strings(names)
+		return []*Finding{newFinding(RulePrMultiDir, "", chartErrorf(ErrMultipleFolders, "", PrMultiDir, names))}
+	}
+	folder := strings.SplitN(changed[0], "/", 2)[0]
+	if folder != s.Title.Folder {
+		f := newFinding(RulePrFolderMismatch, "",
+			chartErrorf(ErrFolderMismatch, folder, PrFolderDif, folder, s.Title.Folder).withValues(s.Title.Folder, folder))
+		f.Chart = folder
+		return []*Finding{f}
+	}
+
+	var findings []*Finding
+	exists := s.Base != nil && fsDirExists(s.Base, folder)
+	switch {
+	case s.Title.PrType == PrTypeNew && exists:
+		findings = append(findings, newFinding(RulePrFolderExists, "", chartErrorf(ErrFolderExists, folder, PrFolderExist, folder)))
+	case s.Title.PrType != PrTypeNew && !exists:
+		findings = append(findings, newFinding(RulePrFolderMissing, "", chartErrorf(ErrFolderNotExist, folder, PrNotExist, folder)))
+	}
+
+	var remove, suspend bool
+	for _, p := range changed {
+		switch strings.TrimPrefix(p, folder+"/") {
+		case RemoveFile:
+			remove = true
+		case SuspendFile:
+			suspend = true
+		}
+	}
+	switch s.Title.PrType {
+	case PrTypeRemove:
+		if !remove || len(changed) > 1 {
+			findings = append(findings, newFinding(RulePrControlFiles, RemoveFile,
+				chartErrorf(ErrControlFiles, folder, PrShouldOnlyIncludeRemove).withField(RemoveFile)))
+		}
+	case PrTypeSuspend:
+		if !suspend || remove {
+			findings = append(findings, newFinding(RulePrControlFiles, SuspendFile,
+				chartErrorf(ErrControlFiles, folder, PrSuspendShould).withField(SuspendFile)))
+		}
+	default:
+		if remove || suspend {
+			findings = append(findings, newFinding(RulePrControlFiles, "", chartErrorf(ErrControlFiles, folder, PrSpecialFiles)))
+		}
+	}
+	if s.Title.PrType == PrTypeUpdate && exists && changes(changed, path.Join(folder, OwnersFile)) {
+		findings = append(findings, checkOwnersChange(s, folder)...)
+	}
+	for _, f := range findings {
+		f.Chart = folder
+	}
+	return findings
+}
+
+// checkOwnersChange checks the owners file of folder changed by the UPDATE
+// PR s.
+func checkOwnersChange(s *Submission, folder string) []*Finding {
+	var findings []*Finding
+	base, err := LoadOwners(s.Base, folder)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		findings = append(findings, newFinding(RulePrPermission, OwnersFile, err))
+	} else if err := base.Authorize(folder, s.Actor); err != nil {
+		findings = append(findings, newFinding(RulePrPermission, OwnersFile, err))
+	}
+	if s.Head == nil {
+		return findings
+	}
+	head, err := LoadOwners(s.Head, folder)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return append(findings, newFinding(RulePrOwners, OwnersFile, err))
+	}
+	if err := head.Validate(folder); err != nil {
+		findings = append(findings, newFinding(RulePrOwners, OwnersFile, err))
+	}
+	return findings
+}
+
+func changes(changed []string, name string) bool {
+	for _, p := range changed {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVersionIncrease compares the chart folder head of an UPDATE PR with
+// base, the same folder on the base branch: the Chart.yaml version and
+// metadata.version of OlaresManifest.yaml must be greater than in base under
+// semver, a change of spec.versionName needs that bump and a bump needs
+// spec.upgradeDescription. The error holds the findings, ChartErrors of kind
+// ErrVersionNotIncreased or ErrMissingUpgradeDesc, or the first failure
+// loading either folder.
+func CheckVersionIncrease(base, head string) error {
+	return findingsErr(checkVersionIncrease(newChartBundle(base, nil), newChartBundle(head, nil)))
+}
+
+// CheckVersionIncreaseFS is CheckVersionIncrease for the chart folder dir of
+// the repositories base and head, e.g. Submission.Base and Submission.Head.
+func CheckVersionIncreaseFS(base, head fs.FS, dir string) error {
+	baseBundle, err := newChartBundleFS(base, dir, nil)
+	if err != nil {
+		return err
+	}
+	headBundle, err := newChartBundleFS(head, dir, nil)
+	if err != nil {
+		return err
+	}
+	return findingsErr(checkVersionIncrease(baseBundle, headBundle))
+}
+
+func checkVersionIncrease(base, head *ChartBundle) []*Finding {
+	// the base only provides the versions to compare with, the folder
+	// rules of today may not have held when it was merged
+	baseChart, err := base.chartYaml()
+	if err != nil {
+		return asFindings(err, RuleChartYaml, "")
+	}
+	baseConf, err := base.appConfiguration()
+	if err != nil {
+		return asFindings(err, RuleManifestLoad, ManifestName)
+	}
+	headChart, headConf, folder, err := bundleFolderCheck(head)
+	if err != nil {
+		return asFindings(err, RuleChartYaml, "")
+	}
+
+	var findings []*Finding
+	bumped := true
+	for _, v := range []struct {
+		file, field string
+		base, head  string
+	}{
+		{"Chart.yaml", "Version", baseChart.Version, headChart.Version},
+		{ManifestName, "Metadata.Version", baseConf.Metadata.Version, headConf.Metadata.Version},
+	} {
+		if err := versionIncreased(folder, v.field, v.base, v.head); err != nil {
+			bumped = false
+			f := newFinding(RulePrVersionIncrease, v.file, err)
+			if v.file == ManifestName {
+				f.withPath(v.field)
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	if baseConf.Spec.VersionName != headConf.Spec.VersionName && !bumped {
+		findings = append(findings, newFinding(RulePrVersionName, ManifestName,
+			chartErrorf(ErrVersionNotIncreased, folder, VersionNameNeedsBump, baseConf.Spec.VersionName, headConf.Spec.VersionName, headChart.Version).
+				withField("Spec.VersionName").withValues(baseConf.Spec.VersionName, headConf.Spec.VersionName)).
+			withPath("Spec.VersionName"))
+	}
+	if bumped && strings.TrimSpace(headConf.Spec.UpgradeDescription) == "" {
+		findings = append(findings, newFinding(RulePrUpgradeDesc, ManifestName,
+			chartErrorf(ErrMissingUpgradeDesc, folder, MissingUpgradeDescription, baseChart.Version, headChart.Version).
+				withField("Spec.UpgradeDescription")).
+			withPath("Spec.UpgradeDescription"))
+	}
+	head.locator.annotate(findings)
+	return findings
+}
+
+// versionIncreased checks that the version head of field is greater than
+// base.
+func versionIncreased(folder, field, base, head string) error {
+	mustIncrease := func(err error) error {
+		return chartErrorf(ErrVersionNotIncreased, folder, PrVersionMustIncrease, head, base).
+			withField(field).withValues(base, head).wrap(err)
+	}
+	headVersion, err := semver.NewVersion(head)
+	if err != nil {
+		return mustIncrease(err)
+	}
+	baseVersion, err := semver.NewVersion(base)
+	if err != nil {
+		// a base version semver cannot parse was accepted before, any valid
+		// version replaces it
+		return nil
+	}
+	if !headVersion.GreaterThan(baseVersion) {
+		return mustIncrease(nil)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package oachecker
+
+import "sort"
+
+// Rule describes one check of the linter. Findings carry the ID of the rule
+// that produced them.
+type Rule struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Stage       string   `json:"stage"`
+	Severity    Severity `json:"severity"`
+	Enabled     bool     `json:"enabled"`
+	Description string   `json:"description"`
+}
+
+var ruleRegistry = []Rule{
+	{ID: RuleManifestLoad, Name: "manifest-load", Stage: StageLoad, Severity: SeverityError, Enabled: true,
+		Description: "OlaresManifest.yaml must exist, render with the fake owner/admin values and parse."},
+	{ID: RuleManifestSchema, Name: "manifest-schema", Stage: StageManifest, Severity: SeverityError, Enabled: true,
+		Description: "OlaresManifest.yaml fields must satisfy the schema expressions of AppConfiguration."},
+	{ID: RuleSupportArch, Name: "support-arch", Stage: StageManifest, Severity: SeverityError, Enabled: true,
+		Description: "spec.supportArch must be non-empty and only list known architectures."},
+	{ID: RuleEntranceName, Name: "entrance-name-unique", Stage: StageManifest, Severity: SeverityError, Enabled: true,
+		Description: "Entrance names must be unique."},
+	{ID: RuleAppData, Name: "appdata-permission", Stage: StageCustom, Severity: SeverityError, Enabled: true,
+		Description: "Templates using .Values.userspace.appdata must set permission.appData. Runs with WithAppDataValidator."},
+	{ID: RuleCustomValidator, Name: "custom-validator", Stage: StageCustom, Severity: SeverityError, Enabled: true,
+		Description: "Errors returned by custom validators."},
+	{ID: RuleChartRender, Name: "chart-render", Stage: StageResource, Severity: SeverityError, Enabled: true,
+		Description: "The chart must render with the fake values used for the dry run."},
+	{ID: RuleAppResourceLimit, Name: "app-request-limit", Stage: StageResource, Severity: SeverityError, Enabled: true,
+		Description: "spec.requiredCpu/requiredMemory must not exceed spec.limitedCpu/limitedMemory."},
+	{ID: RuleContainerResources, Name: "container-request-limit", Stage: StageResource, Severity: SeverityError, Enabled: true,
+		Description: "Every deployment/statefulset container must set cpu and memory requests and limits, requests not above limits."},
+	{ID: RuleResourceSum, Name: "resource-sum", Stage: StageResource, Severity: SeverityError, Enabled: true,
+		Description: "The sum of container requests and limits must fit into the manifest spec."},
+	{ID: RuleUploadMount, Name: "upload-mount", Stage: StageResource, Severity: SeverityError, Enabled: true,
+		Description: "options.upload.dest must be mounted by a container."},
+	{ID: RuleDeploymentName, Name: "deployment-name", Stage: StageResource, Severity: SeverityError, Enabled: true,
+		Description: "An app must have a deployment or statefulset named after the app."},
+	{ID: RuleResourceNamespace, Name: "resource-namespace", Stage: StageResource, Severity: SeverityError, Enabled: false,
+		Description: "Rendered resources must live in the app namespace or a user-system namespace."},
+	{ID: RuleServiceAccountRole, Name: "service-account-rbac", Stage: StageResource, Severity: SeverityError, Enabled: false,
+		Description: "Roles bound to service accounts must not grant write access to nodes or network policies."},
+	{ID: RuleFolderName, Name: "folder-name", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "The chart folder name must match ^[a-z0-9]{1,30}$."},
+	{ID: RuleFolderExists, Name: "folder-exists", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "The chart folder must exist."},
+	{ID: RuleChartYaml, Name: "chart-yaml", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "Chart.yaml must exist and parse."},
+	{ID: RuleChartFields, Name: "chart-fields", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "Chart.yaml must set apiVersion, name and version."},
+	{ID: RuleValuesYaml, Name: "values-yaml", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "values.yaml must exist."},
+	{ID: RuleTemplatesFolder, Name: "templates-folder", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "The templates folder must exist."},
+	{ID: RuleManifestFile, Name: "manifest-file", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "OlaresManifest.yaml must exist in the chart folder."},
+	{ID: RuleNameConsistency, Name: "name-consistency", Stage: StageSameVersion, Severity: SeverityError, Enabled: true,
+		Description: "Chart.yaml name, folder name and metadata.name must be the same."},
+	{ID: RuleVersionConsistency, Name: "version-consistency", Stage: StageSameVersion, Severity: SeverityError, Enabled: true,
+		Description: "Chart.yaml version and metadata.version must be the same."},
+	{ID: RuleCategories, Name: "categories", Stage: StageFolder, Severity: SeverityError, Enabled: false,
+		Description: "metadata.categories must be non-empty and only use known categories."},
+	{ID: RuleReservedFolderName, Name: "reserved-folder-name", Stage: StageFolder, Severity: SeverityError, Enabled: false,
+		Description: "The chart folder name must not be a reserved word."},
+	{ID: RuleImages, Name: "images", Stage: StageFolder, Severity: SeverityError, Enabled: true,
+		Description: "Icon and promote images must have a supported format and size."},
+	{ID: RuleSuppressionUnused, Name: "suppression-unused", Stage: StageSuppression, Severity: SeverityWarning, Enabled: true,
+		Description: "An oachecker:ignore comment must match a finding of a rule that ran."},
+	{ID: RuleSuppressionReason, Name: "suppression-reason", Stage: StageSuppression, Severity: SeverityWarning, Enabled: true,
+		Description: "An oachecker:ignore comment must give a reason=..."},
+	{ID: RuleLintCanceled, Name: "lint-canceled", Stage: StageRun, Severity: SeverityError, Enabled: true,
+		Description: "The lint run was canceled or timed out before all stages ran."},
+	{ID: RuleLintPanic, Name: "lint-panic", Stage: StageRun, Severity: SeverityError, Enabled: true,
+		Description: "A check panicked while linting the chart."},
+	{ID: RuleConfigLoad, Name: "config-load", Stage: StageRun, Severity: SeverityError, Enabled: true,
+		Description: "The .oachecker.yaml files of the chart must parse."},
+	{ID: RulePrMultiDir, Name: "pr-single-folder", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "A pull request must only change files of one chart folder."},
+	{ID: RulePrFolderMismatch, Name: "pr-title-folder", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "The folder changed by a pull request must be the folder named in its title."},
+	{ID: RulePrFolderExists, Name: "pr-new-folder", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "A NEW pull request must add a folder not yet in the base branch."},
+	{ID: RulePrFolderMissing, Name: "pr-existing-folder", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "UPDATE, REMOVE and SUSPEND pull requests must change a folder of the base branch."},
+	{ID: RulePrControlFiles, Name: "pr-control-files", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "REMOVE pull requests only add .remove, SUSPEND pull requests add .suspend and other pull requests no control file."},
+	{ID: RulePrOwners, Name: "pr-owners-file", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "An owners file changed by an UPDATE pull request must list distinct, well-formed GitHub logins."},
+	{ID: RulePrPermission, Name: "pr-owners-permission", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "Only an owner listed in the base branch may change the owners file of an UPDATE pull request."},
+	{ID: RulePrVersionIncrease, Name: "pr-version-increase", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "The Chart.yaml version and metadata.version must be greater than those of the base branch."},
+	{ID: RulePrVersionName, Name: "pr-version-name", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "A change of spec.versionName needs a version bump."},
+	{ID: RulePrUpgradeDesc, Name: "pr-upgrade-description", Stage: StageSubmission, Severity: SeverityError, Enabled: true,
+		Description: "A version bump needs spec.upgradeDescription in OlaresManifest.yaml."},
+}
+
+var rulesByID = func() map[string]Rule {
+	m := make(map[string]Rule, len(ruleRegistry))
+	for _, r := range ruleRegistry {
+		m[r.ID] = r
+	}
+	return m
+}()
+
+// Rules returns the metadata of every registered rule sorted by ID.
+func Rules() []Rule {
+	rules := make([]Rule, len(ruleRegistry))
+	copy(rules, ruleRegistry)
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// LookupRule returns the rule registered under id.
+func LookupRule(id string) (Rule, bool) {
+	r, ok := rulesByID[id]
+	return r, ok
+}
+
+func defaultSeverity(ruleID string) Severity {
+	if r, ok := rulesByID[ruleID]; ok {
+		return r.Severity
+	}
+	return SeverityError
+}
+
+// ruleEnabled reports whether findings of ruleID are reported, taking the
+// overrides of o into account. Unknown rule ids are always enabled.
+func (o *LintOptions) ruleEnabled(ruleID string) bool {
+	if o != nil {
+		if enabled, ok := o.RuleEnabled[ruleID]; ok {
+			return enabled
+		}
+	}
+	if r, ok := rulesByID[ruleID]; ok {
+		return r.Enabled
+	}
+	return true
+}
+
+// checkedRules returns the ids of the rules of stages that are enabled by o.
+func (o *LintOptions) checkedRules(stages map[string]bool) []string {
+	var ids []string
+	for _, r := range ruleRegistry {
+		if stages[r.Stage] && o.ruleEnabled(r.ID) {
+			ids = append(ids, r.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// applyRules drops findings of disabled rules and applies severity overrides.
+func (o *LintOptions) applyRules(findings []*Finding) []*Finding {
+	var ret []*Finding
+	for _, f := range findings {
+		if !o.ruleEnabled(f.RuleID) {
+			continue
+		}
+		if o != nil {
+			if s, ok := o.RuleSeverity[f.RuleID]; ok {
+				f.Severity = s
+			}
+		}
+		ret = append(ret, f)
+	}
+	return ret
+}
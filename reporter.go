@@ -0,0 +1,302 @@
+package oachecker
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Reporter writes the reports of a lint run to one sink.
+type Reporter interface {
+	Report(reports []*Report) error
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(reports []*Report) error
+
+func (f ReporterFunc) Report(reports []*Report) error {
+	return f(reports)
+}
+
+// names of the built-in reporter formats
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatSARIF  = "sarif"
+	FormatJUnit  = "junit"
+	FormatGitHub = "github"
+)
+
+// Formats lists the formats accepted by NewReporter.
+var Formats = []string{FormatText, FormatJSON, FormatSARIF, FormatJUnit, FormatGitHub}
+
+// NewReporter returns the built-in reporter for format writing to w.
+func NewReporter(format string, w io.Writer) (Reporter, error) {
+	switch format {
+	case FormatText:
+		return NewTextReporter(w), nil
+	case FormatJSON:
+		return NewJSONReporter(w), nil
+	case FormatSARIF:
+		return NewSARIFReporter(w), nil
+	case FormatJUnit:
+		return NewJUnitReporter(w), nil
+	case FormatGitHub:
+		return NewGitHubReporter(w), nil
+	}
+	return nil, fmt.Errorf("unknown format %q, must in %v", format, Formats)
+}
+
+// MultiReporter feeds the same reports to every reporter. All reporters run
+// even when one of them fails; their errors are joined.
+func MultiReporter(reporters ...Reporter) Reporter {
+	return ReporterFunc(func(reports []*Report) error {
+		var errs []error
+		for _, r := range reporters {
+			if err := r.Report(reports); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// findingPath is the path of the file a finding points at, relative to the
+// working directory the lint ran in. Findings without a file point at the
+// chart folder.
+func findingPath(report *Report, f *Finding) string {
+	dir := report.Path
+	if dir == "" {
+		dir = report.Chart
+	}
+	return strings.TrimPrefix(path.Join(filepath.ToSlash(filepath.Clean(dir)), f.File), "./")
+}
+
+// NewTextReporter writes one line per finding:
+//
+//	testdata/firefox/OlaresManifest.yaml:34:5: error: unsupport arch: sparc [OAC-MAN-003]
+func NewTextReporter(w io.Writer) Reporter {
+	return ReporterFunc(func(reports []*Report) error {
+		for _, report := range reports {
+			if len(report.Findings) == 0 {
+				if _, err := fmt.Fprintf(w, "%s: no problems found\n", report.Chart); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, f := range report.Findings {
+				location := findingPath(report, f)
+				if f.Line > 0 {
+					location += fmt.Sprintf(":%d", f.Line)
+					if f.Column > 0 {
+						location += fmt.Sprintf(":%d", f.Column)
+					}
+				}
+				if _, err := fmt.Fprintf(w, "%s: %s: %s [%s]\n", location, f.Severity, f.Message, f.RuleID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// NewJSONReporter writes the reports as an indented JSON array.
+func NewJSONReporter(w io.Writer) Reporter {
+	return ReporterFunc(func(reports []*Report) error {
+		if reports == nil {
+			reports = []*Report{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	})
+}
+
+// NewSARIFReporter writes the reports as a SARIF 2.1.0 log.
+func NewSARIFReporter(w io.Writer) Reporter {
+	return ReporterFunc(func(reports []*Report) error {
+		return WriteSARIF(w, reports...)
+	})
+}
+
+// NewGitHubReporter writes GitHub Actions workflow commands, which show up
+// as inline annotations on pull requests.
+func NewGitHubReporter(w io.Writer) Reporter {
+	return ReporterFunc(func(reports []*Report) error {
+		for _, report := range reports {
+			for _, f := range report.Findings {
+				command := "notice"
+				switch f.Severity {
+				case SeverityError:
+					command = "error"
+				case SeverityWarning:
+					command = "warning"
+				}
+				props := []string{"file=" + escapeGitHubProperty(findingPath(report, f))}
+				if f.Line > 0 {
+					props = append(props, fmt.Sprintf("line=%d", f.Line))
+					if f.Column > 0 {
+						props = append(props, fmt.Sprintf("col=%d", f.Column))
+					}
+				}
+				props = append(props, "title="+escapeGitHubProperty(f.RuleID))
+				if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(props, ","), escapeGitHubData(f.Message)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// NewJUnitReporter writes a JUnit XML document with one test suite per chart
+// and one test case per rule. A rule checked by the run, see Report.Rules,
+// passes or fails when it has error findings; its warnings and notes go to
+// system-out. The other rules are marked skipped, with the reason when their
+// stage was skipped because of an earlier failure. Run and submission rules
+// only get a test case when they have findings.
+func NewJUnitReporter(w io.Writer) Reporter {
+	return ReporterFunc(func(reports []*Report) error {
+		doc := junitTestSuites{}
+		for _, report := range reports {
+			suite := junitSuite(report)
+			doc.Suites = append(doc.Suites, suite)
+			doc.Tests += suite.Tests
+			doc.Failures += suite.Failures
+			doc.Skipped += suite.Skipped
+		}
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+}
+
+func junitSuite(report *Report) junitTestSuite {
+	byRule := make(map[string][]*Finding)
+	for _, f := range report.Findings {
+		byRule[f.RuleID] = append(byRule[f.RuleID], f)
+	}
+	skippedStages := make(map[string]string)
+	for _, s := range report.Skipped {
+		skippedStages[s.Stage] = s.Reason
+	}
+
+	checked := make(map[string]bool, len(report.Rules))
+	for _, id := range report.Rules {
+		checked[id] = true
+	}
+
+	var rules []Rule
+	for _, r := range Rules() {
+		if (r.Stage == StageRun || r.Stage == StageSubmission) && len(byRule[r.ID]) == 0 {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	// findings of rules that are not registered, e.g. from custom validators
+	seen := make(map[string]bool)
+	for _, f := range report.Findings {
+		if _, ok := LookupRule(f.RuleID); !ok && !seen[f.RuleID] {
+			seen[f.RuleID] = true
+			rules = append(rules, Rule{ID: f.RuleID, Name: f.RuleID})
+		}
+	}
+
+	suite := junitTestSuite{Name: report.Chart}
+	for _, r := range rules {
+		tc := junitTestCase{
+			Name:      r.ID + " " + r.Name,
+			ClassName: report.Chart,
+		}
+		var failures, others []string
+		for _, f := range byRule[r.ID] {
+			line := findingPath(report, f)
+			if f.Line > 0 {
+				line += fmt.Sprintf(":%d", f.Line)
+			}
+			line += ": " + f.Message
+			if f.Severity == SeverityError {
+				failures = append(failures, line)
+			} else {
+				others = append(others, string(f.Severity)+": "+line)
+			}
+		}
+		switch {
+		case len(failures) > 0:
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d finding(s)", len(failures)),
+				Type:    r.ID,
+				Text:    strings.Join(failures, "\n"),
+			}
+			suite.Failures++
+		case len(byRule[r.ID]) == 0 && !checked[r.ID]:
+			reason := skippedStages[r.Stage]
+			if reason == "" {
+				reason = "not checked"
+			}
+			tc.Skipped = &junitSkipped{Message: reason}
+			suite.Skipped++
+		}
+		if len(others) > 0 {
+			tc.SystemOut = strings.Join(others, "\n")
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	return suite
+}
@@ -1,9 +1,7 @@
 package oachecker
 
 import (
-	"errors"
-	"fmt"
-	"io"
+	"context"
 	"path/filepath"
 	"strings"
 
@@ -13,8 +11,8 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
-func checkResourceLimit(resources kube.ResourceList, cfg *AppConfiguration) error {
-	errs := make([]error, 0)
+func checkResourceLimit(resources kube.ResourceList, cfg *AppConfiguration) []*Finding {
+	findings := make([]*Finding, 0)
 	rcpu, _ := resource.ParseQuantity(cfg.Spec.RequiredCPU)
 	rmemory, _ := resource.ParseQuantity(cfg.Spec.RequiredMemory)
 	lcpu, _ := resource.ParseQuantity(cfg.Spec.LimitedCPU)
@@ -26,11 +24,11 @@ func checkResourceLimit(resources kube.ResourceList, cfg *AppConfiguration) erro
 	appLimitedMemory := lmemory.AsApproximateFloat64()
 
 	if appRequiredCPU > appLimitedCPU {
-		errs = append(errs, fmt.Errorf("spec.requiredCpu should less than spec.limitedCpu"))
+		findings = append(findings, findingf(RuleAppResourceLimit, ManifestName, "spec.requiredCpu should less than spec.limitedCpu").withPath("Spec.RequiredCPU"))
 	}
 
 	if appRequiredMemory > appLimitedMemory {
-		errs = append(errs, fmt.Errorf("spec.requiredMemory should less than spec.limitedMemeory"))
+		findings = append(findings, findingf(RuleAppResourceLimit, ManifestName, "spec.requiredMemory should less than spec.limitedMemeory").withPath("Spec.RequiredMemory"))
 	}
 
 	limitCPU, limitMemory := float64(0), float64(0)
@@ -42,35 +40,35 @@ func checkResourceLimit(resources kube.ResourceList, cfg *AppConfiguration) erro
 			var deployment v1.Deployment
 			err := scheme.Scheme.Convert(r.Object, &deployment, nil)
 			if err != nil {
-				return err
+				return append(findings, newFinding(RuleContainerResources, "", err).withSource(r))
 			}
 			for _, c := range deployment.Spec.Template.Spec.Containers {
 				requests := c.Resources.Requests
 				limits := c.Resources.Limits
 				if !requests.Cpu().IsZero() && !limits.Cpu().IsZero() && requests.Cpu().Cmp(*limits.Cpu()) > 0 {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s requests.cpu must small than limits.cpu", deployment.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s requests.cpu must small than limits.cpu", deployment.Name, c.Name).withSource(r))
 				}
 				if !requests.Memory().IsZero() && !limits.Memory().IsZero() && requests.Memory().Cmp(*limits.Memory()) > 0 {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s requests.memory must small than limits.memory", deployment.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s requests.memory must small than limits.memory", deployment.Name, c.Name).withSource(r))
 				}
 
 				if requests.Memory().IsZero() {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s must set memory request", deployment.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s must set memory request", deployment.Name, c.Name).withSource(r))
 				} else {
 					requiredMemory += requests.Memory().AsApproximateFloat64()
 				}
 				if requests.Cpu().IsZero() {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s must set cpu request", deployment.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s must set cpu request", deployment.Name, c.Name).withSource(r))
 				} else {
 					requiredCPU += requests.Cpu().AsApproximateFloat64()
 				}
 				if limits.Memory().IsZero() {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s must set memory limit", deployment.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s must set memory limit", deployment.Name, c.Name).withSource(r))
 				} else {
 					limitMemory += limits.Memory().AsApproximateFloat64()
 				}
 				if limits.Cpu().IsZero() {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s must set cpu limit", deployment.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s must set cpu limit", deployment.Name, c.Name).withSource(r))
 				} else {
 					limitCPU += limits.Cpu().AsApproximateFloat64()
 				}
@@ -80,34 +78,34 @@ func checkResourceLimit(resources kube.ResourceList, cfg *AppConfiguration) erro
 			var sts v1.StatefulSet
 			err := scheme.Scheme.Convert(r.Object, &sts, nil)
 			if err != nil {
-				return err
+				return append(findings, newFinding(RuleContainerResources, "", err).withSource(r))
 			}
 			for _, c := range sts.Spec.Template.Spec.Containers {
 				requests := c.Resources.Requests
 				limits := c.Resources.Limits
 				if !requests.Cpu().IsZero() && !limits.Cpu().IsZero() && requests.Cpu().Cmp(*limits.Cpu()) > 0 {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s requests.cpu must small than limits.cpu", sts.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s requests.cpu must small than limits.cpu", sts.Name, c.Name).withSource(r))
 				}
 				if !requests.Memory().IsZero() && !limits.Memory().IsZero() && requests.Memory().Cmp(*limits.Memory()) > 0 {
-					errs = append(errs, fmt.Errorf("deployment: %s, container: %s requests.memory must small than limits.memory", sts.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "deployment: %s, container: %s requests.memory must small than limits.memory", sts.Name, c.Name).withSource(r))
 				}
 				if requests.Memory().IsZero() {
-					errs = append(errs, fmt.Errorf("statefulset: %s, container: %s must set memory request", sts.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "statefulset: %s, container: %s must set memory request", sts.Name, c.Name).withSource(r))
 				} else {
 					requiredMemory += requests.Memory().AsApproximateFloat64()
 				}
 				if requests.Cpu().IsZero() {
-					errs = append(errs, fmt.Errorf("statefulset: %s, container: %s must set cpu request", sts.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "statefulset: %s, container: %s must set cpu request", sts.Name, c.Name).withSource(r))
 				} else {
 					requiredCPU += requests.Cpu().AsApproximateFloat64()
 				}
 				if limits.Memory().IsZero() {
-					errs = append(errs, fmt.Errorf("statefulset: %s, container: %s must set memory limit", sts.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "statefulset: %s, container: %s must set memory limit", sts.Name, c.Name).withSource(r))
 				} else {
 					limitMemory += limits.Memory().AsApproximateFloat64()
 				}
 				if limits.Cpu().IsZero() {
-					errs = append(errs, fmt.Errorf("statefulset: %s, container: %s must set cpu limit", sts.Name, c.Name))
+					findings = append(findings, findingf(RuleContainerResources, "", "statefulset: %s, container: %s must set cpu limit", sts.Name, c.Name).withSource(r))
 				} else {
 					limitCPU += limits.Cpu().AsApproximateFloat64()
 				}
@@ -115,43 +113,48 @@ func checkResourceLimit(resources kube.ResourceList, cfg *AppConfiguration) erro
 		}
 	}
 	if limitCPU > appLimitedCPU {
-		errs = append(errs, fmt.Errorf("sum of all containers resources limits cpu should less than OlaresManifest.yaml spec.limitedCpu"))
+		findings = append(findings, findingf(RuleResourceSum, ManifestName, "sum of all containers resources limits cpu should less than OlaresManifest.yaml spec.limitedCpu").withPath("Spec.LimitedCPU"))
 	}
 	if limitMemory > appLimitedMemory {
-		errs = append(errs, fmt.Errorf("sum of all containers resources limits memory should less than OlaresManifest.yaml spec.limitedMemory"))
+		findings = append(findings, findingf(RuleResourceSum, ManifestName, "sum of all containers resources limits memory should less than OlaresManifest.yaml spec.limitedMemory").withPath("Spec.LimitedMemory"))
 	}
 	if requiredCPU > appRequiredCPU {
-		errs = append(errs, fmt.Errorf("sum of all containers resources requests cpu should less than OlaresManifest.yaml spec.requiredCpu"))
+		findings = append(findings, findingf(RuleResourceSum, ManifestName, "sum of all containers resources requests cpu should less than OlaresManifest.yaml spec.requiredCpu").withPath("Spec.RequiredCPU"))
 	}
 	if requiredMemory > appRequiredMemory {
-		errs = append(errs, fmt.Errorf("sum of all containers resources requests memory should less than OlaresManifest.yaml spec.requiredMemory"))
+		findings = append(findings, findingf(RuleResourceSum, ManifestName, "sum of all containers resources requests memory should less than OlaresManifest.yaml spec.requiredMemory").withPath("Spec.RequiredMemory"))
 	}
-	return AggregateErr(errs)
+	return findings
 }
 
 func CheckResource(oacPath string, cfg *AppConfiguration, options *LintOptions) error {
-	resources, err := getResourceListFromChart(oacPath, cfg, options)
-	if err != nil && !errors.Is(err, io.EOF) {
-		return err
-	}
-	err = checkResourceLimit(resources, cfg)
-	if err != nil {
-		return err
-	}
-	err = checkUploadConfig(resources, cfg)
-	if err != nil {
-		return err
-	}
-	err = checkDeploymentName(resources, cfg)
-	if err != nil {
-		return err
-	}
+	return CheckResourceContext(context.Background(), oacPath, cfg, options)
+}
+
+// CheckResourceContext is CheckResource with the Helm dry run bounded by ctx.
+func CheckResourceContext(ctx context.Context, oacPath string, cfg *AppConfiguration, options *LintOptions) error {
+	return findingsErr(checkResource(ctx, oacPath, cfg, options))
+}
 
-	return nil
+func checkResource(ctx context.Context, oacPath string, cfg *AppConfiguration, options *LintOptions) []*Finding {
+	b := newChartBundle(oacPath, options)
+	b.manifestLoaded, b.Manifest = true, cfg
+	return b.resourceFindings(ctx)
+}
+
+// checkResourceList runs the checks on the resources of a dry run.
+func checkResourceList(resources kube.ResourceList, cfg *AppConfiguration, options *LintOptions) []*Finding {
+	findings := checkResourceLimit(resources, cfg)
+	findings = append(findings, checkUploadConfig(resources, cfg)...)
+	findings = append(findings, checkDeploymentName(resources, cfg)...)
+	findings = append(findings, checkResourceNamespace(resources)...)
+	findings = append(findings, checkServiceAccountRules(resources)...)
+
+	return options.applyRules(findings)
 }
 
 // checkDeploymentName for app we assume must have one deployment/sts name equal app name
-func checkDeploymentName(resources kube.ResourceList, cfg *AppConfiguration) error {
+func checkDeploymentName(resources kube.ResourceList, cfg *AppConfiguration) []*Finding {
 	if cfg.ConfigType != "app" {
 		return nil
 	}
@@ -169,29 +172,29 @@ func checkDeploymentName(resources kube.ResourceList, cfg *AppConfiguration) err
 			}
 		}
 	}
-	return fmt.Errorf("must have a deployment/sts name equal app name %s", appName)
+	return []*Finding{findingf(RuleDeploymentName, ManifestName, "must have a deployment/sts name equal app name %s", appName).withPath("Metadata.Name")}
 }
 
-func checkResourceNamespace(resources kube.ResourceList) error {
-	errs := make([]error, 0)
+func checkResourceNamespace(resources kube.ResourceList) []*Finding {
+	findings := make([]*Finding, 0)
 	for _, r := range resources {
 		kind := r.Object.GetObjectKind().GroupVersionKind().Kind
 		if kind == Deployment || kind == StatefulSet || kind == DaemonSet {
 			if r.Namespace != "app-namespace" {
-				err := fmt.Errorf("illegal namespace: %s for %s, name %s", r.Namespace, kind, r.Name)
-				errs = append(errs, err)
+				f := findingf(RuleResourceNamespace, "", "illegal namespace: %s for %s, name %s", r.Namespace, kind, r.Name).withSource(r)
+				findings = append(findings, f)
 			}
 		} else {
 			if r.Namespace != "app-namespace" && !strings.HasPrefix(r.Namespace, "user-system-") {
-				err := fmt.Errorf("illegal namespace: %s for %s, name %s", r.Namespace, kind, r.Name)
-				errs = append(errs, err)
+				f := findingf(RuleResourceNamespace, "", "illegal namespace: %s for %s, name %s", r.Namespace, kind, r.Name).withSource(r)
+				findings = append(findings, f)
 			}
 		}
 	}
-	return AggregateErr(errs)
+	return findings
 }
 
-func checkUploadConfig(resources kube.ResourceList, cfg *AppConfiguration) error {
+func checkUploadConfig(resources kube.ResourceList, cfg *AppConfiguration) []*Finding {
 	if cfg.Options.Upload == nil {
 		return nil
 	}
@@ -202,7 +205,7 @@ func checkUploadConfig(resources kube.ResourceList, cfg *AppConfiguration) error
 			var deployment v1.Deployment
 			err = scheme.Scheme.Convert(r.Object, &deployment, nil)
 			if err != nil {
-				return err
+				return []*Finding{newFinding(RuleUploadMount, "", err).withSource(r)}
 			}
 			for _, c := range deployment.Spec.Template.Spec.Containers {
 				for _, v := range c.VolumeMounts {
@@ -216,11 +219,7 @@ func checkUploadConfig(resources kube.ResourceList, cfg *AppConfiguration) error
 			var sts v1.StatefulSet
 			err = scheme.Scheme.Convert(r.Object, &sts, nil)
 			if err != nil {
-				return err
-			}
-			err = scheme.Scheme.Convert(r.Object, &sts, nil)
-			if err != nil {
-				return err
+				return []*Finding{newFinding(RuleUploadMount, "", err).withSource(r)}
 			}
 			for _, c := range sts.Spec.Template.Spec.Containers {
 				for _, v := range c.VolumeMounts {
@@ -231,5 +230,5 @@ func checkUploadConfig(resources kube.ResourceList, cfg *AppConfiguration) error
 			}
 		}
 	}
-	return fmt.Errorf("can not find volumemount path equal upload Dest: %s", cfg.Options.Upload.Dest)
+	return []*Finding{findingf(RuleUploadMount, ManifestName, "can not find volumemount path equal upload Dest: %s", cfg.Options.Upload.Dest).withPath("Options.Upload.Dest")}
 }
@@ -8,14 +8,9 @@ import (
 	"github.com/thoas/go-funk"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
-	helmLoader "helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
-	"helm.sh/helm/v3/pkg/cli"
-	"helm.sh/helm/v3/pkg/downloader"
-	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
-	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	"io"
@@ -178,7 +173,7 @@ func checkRule(attrs []rbacv1.PolicyRule, rules []rbacv1.PolicyRule) bool {
 }
 
 func actionConfig() (*action.Configuration, error) {
-	registryClient, err := registry.NewClient()
+	registryClient, err := newRegistryClient(context.Background(), nil, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -203,47 +198,74 @@ func InitAction() (*action.Install, error) {
 	return instAction, nil
 }
 
-func getChart(instAction *action.Install, filepath string) (*chart.Chart, error) {
-	cp, err := instAction.ChartPathOptions.LocateChart(filepath, &cli.EnvSettings{})
+// RenderChart dry runs the chart folder at oacPath with the fake values of
+// the resource checks, the owner and admin of options and options.Values,
+// and returns the release manifest.
+func RenderChart(ctx context.Context, oacPath string, options *LintOptions) (string, error) {
+	if options == nil {
+		options = DefaultLintOptions()
+	}
+	b := newChartBundle(oacPath, options)
+	cfg, err := b.appConfiguration()
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	c, err := b.helmChart()
+	if err != nil {
+		return "", err
 	}
-	p := getter.All(&cli.EnvSettings{})
-	chartRequested, err := helmLoader.Load(cp)
+	return renderChart(ctx, c, cfg, options)
+}
+
+// renderResources dry runs chartRequested with fake values and decodes the
+// resources of the release manifest.
+func renderResources(ctx context.Context, chartRequested *chart.Chart, cfg *AppConfiguration, options *LintOptions) (resources kube.ResourceList, err error) {
+	manifest, err := renderChart(ctx, chartRequested, cfg, options)
 	if err != nil {
 		return nil, err
 	}
-	if req := chartRequested.Metadata.Dependencies; req != nil {
-		if err := action.CheckDependencies(chartRequested, req); err != nil {
-			if instAction.DependencyUpdate {
-				man := &downloader.Manager{
-					ChartPath:  cp,
-					Keyring:    instAction.ChartPathOptions.Keyring,
-					SkipUpdate: false,
-					Getters:    p,
-				}
-				if err := man.Update(); err != nil {
-					return nil, err
-				}
-				// Reload the chart with the updated Chart.lock file.
-				if chartRequested, err = helmLoader.Load(cp); err != nil {
-					return nil, err
+	var metadataAccessor = meta.NewAccessor()
+	locator := newResourceLocator(chartRequested)
+	for _, doc := range splitRenderedManifest(manifest) {
+		d := yaml.NewYAMLOrJSONDecoder(strings.NewReader(doc.content), 4096)
+		for {
+			ext := runtime.RawExtension{}
+			if err := d.Decode(&ext); err != nil {
+				if err == io.EOF {
+					break
 				}
-			} else {
-
+				return nil, fmt.Errorf("error parsing")
+			}
+			ext.Raw = bytes.TrimSpace(ext.Raw)
+			if len(ext.Raw) == 0 || bytes.Equal(ext.Raw, []byte("null")) {
+				continue
+			}
+			obj, _, err := unstructured.UnstructuredJSONScheme.Decode(ext.Raw, nil, nil)
+			if err != nil {
+				return nil, err
 			}
+			name, _ := metadataAccessor.Name(obj)
+			namespace, _ := metadataAccessor.Namespace(obj)
+			info := &resource.Info{
+				Namespace: namespace,
+				Name:      name,
+				Source:    locator.locate(doc.source, obj.GetObjectKind().GroupVersionKind().Kind),
+				Object:    obj,
+			}
+			resources = append(resources, info)
 		}
 	}
-	return chartRequested, nil
+	return resources, nil
 }
 
-func getResourceListFromChart(oacPath string, cfg *AppConfiguration, options *LintOptions) (resources kube.ResourceList, err error) {
+// renderChart dry runs chartRequested with fake values and returns the
+// release manifest.
+func renderChart(ctx context.Context, chartRequested *chart.Chart, cfg *AppConfiguration, options *LintOptions) (string, error) {
 	instAction, err := InitAction()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	instAction.Namespace = "app-namespace"
-	chartRequested, err := getChart(instAction, oacPath)
 
 	// fake values for helm dry run
 	values := make(map[string]interface{})
@@ -311,38 +333,15 @@ func getResourceListFromChart(oacPath string, cfg *AppConfiguration, options *Li
 		"issuer": "issuer",
 	}
 	values["olaresEnv"] = map[string]interface{}{}
+	if options != nil && len(options.Values) > 0 {
+		mergeValues(values, options.Values)
+	}
 
-	ret, err := instAction.RunWithContext(context.Background(), chartRequested, values)
+	ret, err := instAction.RunWithContext(ctx, chartRequested, values)
 	if err != nil {
-		return nil, err
-	}
-	var metadataAccessor = meta.NewAccessor()
-	d := yaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(ret.Manifest), 4096)
-	for {
-		ext := runtime.RawExtension{}
-		if err := d.Decode(&ext); err != nil {
-			if err == io.EOF {
-				return resources, nil
-			}
-			return nil, fmt.Errorf("error parsing")
-		}
-		ext.Raw = bytes.TrimSpace(ext.Raw)
-		if len(ext.Raw) == 0 || bytes.Equal(ext.Raw, []byte("null")) {
-			continue
-		}
-		obj, _, err := unstructured.UnstructuredJSONScheme.Decode(ext.Raw, nil, nil)
-		if err != nil {
-			return nil, err
-		}
-		name, _ := metadataAccessor.Name(obj)
-		namespace, _ := metadataAccessor.Namespace(obj)
-		info := &resource.Info{
-			Namespace: namespace,
-			Name:      name,
-			Object:    obj,
-		}
-		resources = append(resources, info)
+		return "", err
 	}
+	return ret.Manifest, nil
 }
 
 func EnsureFileExists(filepath string) error {
@@ -353,7 +352,9 @@ func EnsureFileExists(filepath string) error {
 	return nil
 }
 
-func checkServiceAccountRule(resources kube.ResourceList, rules []rbacv1.PolicyRule) bool {
+// serviceAccountRoleNames returns the names of the roles bound to service
+// accounts by RoleBindings and ClusterRoleBindings.
+func serviceAccountRoleNames(resources kube.ResourceList) map[string]struct{} {
 	set := make(map[string]struct{})
 	for _, r := range resources {
 		kind := r.Object.GetObjectKind().GroupVersionKind().Kind
@@ -382,54 +383,92 @@ func checkServiceAccountRule(resources kube.ResourceList, rules []rbacv1.PolicyR
 			}
 		}
 	}
-	var requestedRules []rbacv1.PolicyRule
+	return set
+}
+
+// boundRoles returns the Role and ClusterRole resources named in set.
+func boundRoles(resources kube.ResourceList, set map[string]struct{}) []*resource.Info {
+	var roles []*resource.Info
 	for _, r := range resources {
 		kind := r.Object.GetObjectKind().GroupVersionKind().Kind
-		if kind == Role {
-			role := rbacv1.Role{}
-			err := scheme.Scheme.Convert(r.Object, &role, nil)
-			if err != nil {
-				fmt.Println(err)
-			}
-			if _, ok := set[role.Name]; ok {
-				requestedRules = append(requestedRules, role.Rules...)
-			}
+		if kind != Role && kind != ClusterRole {
+			continue
 		}
-		if kind == ClusterRole {
-			role := rbacv1.ClusterRole{}
-			err := scheme.Scheme.Convert(r.Object, &role, nil)
-			if err != nil {
-				fmt.Println(err)
-			}
-			if _, ok := set[role.Name]; ok {
-				requestedRules = append(requestedRules, role.Rules...)
-			}
+		if _, ok := set[r.Name]; ok {
+			roles = append(roles, r)
 		}
 	}
-	if len(requestedRules) == 0 {
-		return true
-	}
-	return checkRule(requestedRules, rules)
+	return roles
+}
+
+func roleRules(r *resource.Info) ([]rbacv1.PolicyRule, error) {
+	if r.Object.GetObjectKind().GroupVersionKind().Kind == ClusterRole {
+		role := rbacv1.ClusterRole{}
+		err := scheme.Scheme.Convert(r.Object, &role, nil)
+		return role.Rules, err
+	}
+	role := rbacv1.Role{}
+	err := scheme.Scheme.Convert(r.Object, &role, nil)
+	return role.Rules, err
 }
 
 func CheckServiceAccountRole(oacPath string) error {
-	cfg, err := GetAppConfiguration(oacPath)
-	if err != nil {
-		return err
+	return CheckServiceAccountRoleContext(context.Background(), oacPath)
+}
+
+// CheckServiceAccountRoleContext is CheckServiceAccountRole with the Helm dry
+// run bounded by ctx.
+func CheckServiceAccountRoleContext(ctx context.Context, oacPath string) error {
+	return findingsErr(checkServiceAccountRole(ctx, oacPath))
+}
+
+func checkServiceAccountRole(ctx context.Context, oacPath string) []*Finding {
+	return newChartBundle(oacPath, nil).serviceAccountFindings(ctx)
+}
+
+func (b *ChartBundle) serviceAccountFindings(ctx context.Context) []*Finding {
+	if _, err := b.appConfiguration(); err != nil {
+		return asFindings(err, RuleManifestLoad, ManifestName)
+	}
+	resources, err := b.resources(ctx)
+	if f := contextFinding(ctx); f != nil {
+		return []*Finding{f}
 	}
-	resources, err := getResourceListFromChart(oacPath, cfg, nil)
 	if err != nil && !errors.Is(err, io.EOF) {
-		return err
+		return asFindings(err, RuleChartRender, "")
 	}
+	return checkServiceAccountRules(resources)
+}
+
+func checkServiceAccountRules(resources kube.ResourceList) []*Finding {
 	f := io.NopCloser(strings.NewReader(RULES))
 
 	rules, err := getRulesFromFile(f)
 	if err != nil {
-		return err
+		return asFindings(err, RuleServiceAccountRole, "")
 	}
-	allowed := checkServiceAccountRule(resources, rules.Rules)
-	if !allowed {
-		return fmt.Errorf("please check service account role rules,ensure not in %+v", rules.Rules)
+	var findings []*Finding
+	var granting []*resource.Info
+	var requestedRules []rbacv1.PolicyRule
+	for _, role := range boundRoles(resources, serviceAccountRoleNames(resources)) {
+		granted, err := roleRules(role)
+		if err != nil {
+			findings = append(findings, newFinding(RuleServiceAccountRole, "",
+				fmt.Errorf("failed to read the rules of %s %s: %w", role.Object.GetObjectKind().GroupVersionKind().Kind, role.Name, err)).withSource(role))
+			continue
+		}
+		if len(granted) > 0 {
+			granting = append(granting, role)
+			requestedRules = append(requestedRules, granted...)
+		}
 	}
-	return nil
+	if len(requestedRules) == 0 || checkRule(requestedRules, rules.Rules) {
+		return findings
+	}
+	// every requested rule is forbidden, report each role granting them at
+	// the template it was rendered from
+	for _, role := range granting {
+		findings = append(findings, findingf(RuleServiceAccountRole, "", "please check service account role rules,ensure not in %+v", rules.Rules).withSource(role))
+	}
+	return findings
 }
@@ -0,0 +1,185 @@
+package oachecker
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// SARIFLog is the subset of the SARIF 2.1.0 log format written by WriteSARIF.
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string                `json:"name"`
+	Version        string                `json:"version,omitempty"`
+	InformationURI string                `json:"informationUri,omitempty"`
+	Rules          []SARIFRuleDescriptor `json:"rules"`
+}
+
+type SARIFRuleDescriptor struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     SARIFMessage       `json:"shortDescription"`
+	DefaultConfiguration SARIFConfiguration `json:"defaultConfiguration"`
+	Properties           map[string]string  `json:"properties,omitempty"`
+}
+
+type SARIFConfiguration struct {
+	Enabled bool   `json:"enabled"`
+	Level   string `json:"level"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFResult struct {
+	RuleID              string             `json:"ruleId"`
+	RuleIndex           int                `json:"ruleIndex"`
+	Level               string             `json:"level"`
+	Message             SARIFMessage       `json:"message"`
+	Locations           []SARIFLocation    `json:"locations,omitempty"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints,omitempty"`
+	Suppressions        []SARIFSuppression `json:"suppressions,omitempty"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type SARIFSuppression struct {
+	Kind string `json:"kind"`
+}
+
+// sarifLevel maps a Severity onto a SARIF result level.
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// NewSARIF converts reports into a SARIF log with a single run. Every
+// registered rule is listed as a rule descriptor; findings suppressed inline
+// are kept as results with an inSource suppression.
+func NewSARIF(reports ...*Report) *SARIFLog {
+	rules := Rules()
+	driver := SARIFDriver{
+		Name:           "oachecker",
+		Version:        Version,
+		InformationURI: "https://github.com/beclab/oachecker",
+		Rules:          make([]SARIFRuleDescriptor, 0, len(rules)),
+	}
+	ruleIndex := make(map[string]int, len(rules))
+	for i, r := range rules {
+		ruleIndex[r.ID] = i
+		driver.Rules = append(driver.Rules, SARIFRuleDescriptor{
+			ID:               r.ID,
+			Name:             r.Name,
+			ShortDescription: SARIFMessage{Text: r.Description},
+			DefaultConfiguration: SARIFConfiguration{
+				Enabled: r.Enabled,
+				Level:   sarifLevel(r.Severity),
+			},
+			Properties: map[string]string{"stage": r.Stage},
+		})
+	}
+
+	results := make([]SARIFResult, 0)
+	for _, report := range reports {
+		for _, f := range report.Findings {
+			results = append(results, sarifResult(report, f, ruleIndex, false))
+		}
+		for _, f := range report.Suppressed {
+			results = append(results, sarifResult(report, f, ruleIndex, true))
+		}
+	}
+
+	return &SARIFLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []SARIFRun{{
+			Tool:    SARIFTool{Driver: driver},
+			Results: results,
+		}},
+	}
+}
+
+func sarifResult(report *Report, f *Finding, ruleIndex map[string]int, suppressed bool) SARIFResult {
+	index, ok := ruleIndex[f.RuleID]
+	if !ok {
+		index = -1
+	}
+	result := SARIFResult{
+		RuleID:    f.RuleID,
+		RuleIndex: index,
+		Level:     sarifLevel(f.Severity),
+		Message:   SARIFMessage{Text: f.Message},
+		Locations: []SARIFLocation{{
+			PhysicalLocation: SARIFPhysicalLocation{
+				ArtifactLocation: SARIFArtifactLocation{URI: sarifURI(report, f)},
+			},
+		}},
+		PartialFingerprints: map[string]string{"oachecker/v1": Fingerprint(f)},
+	}
+	if f.Line > 0 {
+		result.Locations[0].PhysicalLocation.Region = &SARIFRegion{StartLine: f.Line, StartColumn: f.Column}
+	}
+	if suppressed {
+		result.Suppressions = []SARIFSuppression{{Kind: "inSource"}}
+	}
+	return result
+}
+
+// sarifURI is the location of the finding relative to the working directory
+// the lint ran in, or a file URI for charts given by absolute path.
+func sarifURI(report *Report, f *Finding) string {
+	p := findingPath(report, f)
+	if path.IsAbs(p) {
+		return "file://" + p
+	}
+	return p
+}
+
+// WriteSARIF writes reports to w as an indented SARIF 2.1.0 log.
+func WriteSARIF(w io.Writer, reports ...*Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewSARIF(reports...))
+}
@@ -0,0 +1,235 @@
+// Package server exposes the linter over HTTP:
+//
+//	POST /v1/lint      lint a chart archive, sent raw or as the multipart field "chart"
+//	POST /v1/manifest  check a raw OlaresManifest.yaml
+//	GET  /v1/rules     list the rules of the linter
+//	GET  /healthz      report that the server is up
+//
+// The lint and manifest endpoints take the owner and admin to render with as
+// query parameters; lint also takes all=true to report the findings of every
+// stage and manifest the chart name to report the findings under. Both
+// answer with a oachecker.Report as JSON, errors are JSON objects with an
+// "error" field.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/beclab/oachecker"
+)
+
+// defaults of Options
+const (
+	DefaultMaxBodyBytes = 20 << 20
+	DefaultTimeout      = time.Minute
+)
+
+// Options configure a Server. Zero fields take their defaults.
+type Options struct {
+	// LintOptions returns the options of one lint request,
+	// oachecker.DefaultLintOptions when nil. The owner, admin and all
+	// parameters of the request are applied on top when given.
+	LintOptions func() *oachecker.LintOptions
+	// MaxBodyBytes bounds the size of a request body, DefaultMaxBodyBytes
+	// when 0.
+	MaxBodyBytes int64
+	// Timeout bounds the time spent on one request, DefaultTimeout when 0.
+	Timeout time.Duration
+	// MaxConcurrent bounds the lint and manifest requests served at the same
+	// time, GOMAXPROCS when 0. Requests wait for a slot until their timeout.
+	MaxConcurrent int
+}
+
+// Server is the http.Handler of the lint service.
+type Server struct {
+	options Options
+	sem     chan struct{}
+	mux     *http.ServeMux
+}
+
+// New returns a server with options, which may be nil.
+func New(options *Options) *Server {
+	s := &Server{}
+	if options != nil {
+		s.options = *options
+	}
+	if s.options.LintOptions == nil {
+		s.options.LintOptions = oachecker.DefaultLintOptions
+	}
+	if s.options.MaxBodyBytes <= 0 {
+		s.options.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if s.options.Timeout <= 0 {
+		s.options.Timeout = DefaultTimeout
+	}
+	if s.options.MaxConcurrent <= 0 {
+		s.options.MaxConcurrent = runtime.GOMAXPROCS(0)
+	}
+	s.sem = make(chan struct{}, s.options.MaxConcurrent)
+
+	s.mux = http.NewServeMux()
+	s.mux.Handle("/v1/lint", s.limited(http.MethodPost, s.lint))
+	s.mux.Handle("/v1/manifest", s.limited(http.MethodPost, s.manifest))
+	s.mux.Handle("/v1/rules", method(http.MethodGet, s.rules))
+	s.mux.Handle("/healthz", method(http.MethodGet, s.health))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// method rejects requests with another method than m.
+func method(m string, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != m {
+			w.Header().Set("Allow", m)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		h(w, r)
+	})
+}
+
+// limited bounds h by the timeout, body size and concurrency limits.
+func (s *Server) limited(m string, h http.HandlerFunc) http.Handler {
+	return method(m, func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.options.Timeout)
+		defer cancel()
+		select {
+		case s.sem <- struct{}{}:
+			defer func() { <-s.sem }()
+		case <-ctx.Done():
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, errors.New("too many concurrent requests"))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.options.MaxBodyBytes)
+		h(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) lint(w http.ResponseWriter, r *http.Request) {
+	body, err := chartBody(r)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	defer body.Close()
+
+	options := s.lintOptions(r)
+	if all, _ := strconv.ParseBool(r.URL.Query().Get("all")); all {
+		options.WithCollectAll()
+	}
+	report, err := oachecker.LintArchiveContext(r.Context(), body, options)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		writeJSON(w, http.StatusGatewayTimeout, report)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// chartBody returns the chart archive of r, the multipart field "chart" or
+// the body itself.
+func chartBody(r *http.Request) (io.ReadCloser, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, badRequest(errors.New(`missing multipart field "chart"`))
+		}
+		if err != nil {
+			return nil, badRequest(err)
+		}
+		if part.FormName() == "chart" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+func (s *Server) manifest(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	report := oachecker.LintManifestReport(r.URL.Query().Get("chart"), content, s.lintOptions(r))
+	writeJSON(w, http.StatusOK, report)
+}
+
+// lintOptions returns the options of the request r: the server options with
+// the owner and admin parameters of r, when given, on top.
+func (s *Server) lintOptions(r *http.Request) *oachecker.LintOptions {
+	options := s.options.LintOptions()
+	query := r.URL.Query()
+	if owner := query.Get("owner"); owner != "" {
+		options.WithOwner(owner)
+	}
+	if admin := query.Get("admin"); admin != "" {
+		options.WithAdmin(admin)
+	}
+	return options
+}
+
+func (s *Server) rules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, oachecker.Rules())
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": oachecker.Version})
+}
+
+// requestError is an error caused by the request.
+type requestError struct{ error }
+
+func (e requestError) Unwrap() error { return e.error }
+
+func badRequest(err error) error { return requestError{err} }
+
+// statusOf maps err to the status code of the response.
+func statusOf(err error) int {
+	var tooLarge *http.MaxBytesError
+	var reqErr requestError
+	switch {
+	case errors.As(err, &tooLarge), errors.Is(err, oachecker.ErrArchiveTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &reqErr), errors.Is(err, oachecker.ErrArchiveInvalid), errors.Is(err, oachecker.ErrArchiveUnsafePath):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beclab/oachecker"
+)
+
+const testChart = "../testdata/firefox"
+
+// TestServer tests the endpoints of the lint service
+func TestServer(t *testing.T) {
+	srv := httptest.NewServer(New(&Options{
+		LintOptions: func() *oachecker.LintOptions { return oachecker.DefaultLintOptions().SkipResources() },
+	}))
+	defer srv.Close()
+	archive := tarChart(t, testChart, nil)
+	manifest, err := os.ReadFile(filepath.Join(testChart, oachecker.ManifestName))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("chart", "firefox-1.0.1.tgz")
+	part.Write(archive)
+	mw.Close()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        []byte
+		status      int
+		contains    string
+	}{
+		{"health", http.MethodGet, "/healthz", "", nil, http.StatusOK, `"status": "ok"`},
+		{"rules", http.MethodGet, "/v1/rules", "", nil, http.StatusOK, oachecker.RuleManifestLoad},
+		{"lint raw", http.MethodPost, "/v1/lint", "application/gzip", archive, http.StatusOK, `"chart": "firefox"`},
+		{"lint multipart", http.MethodPost, "/v1/lint?all=true", mw.FormDataContentType(), body.Bytes(), http.StatusOK, `"chart": "firefox"`},
+		{"lint invalid archive", http.MethodPost, "/v1/lint", "application/gzip", []byte("not a chart"), http.StatusBadRequest, `"error"`},
+		{"lint unsafe archive", http.MethodPost, "/v1/lint", "application/gzip",
+			tarChart(t, testChart, map[string]string{"firefox/../evil": "x"}), http.StatusBadRequest, "unsafe path"},
+		{"lint missing field", http.MethodPost, "/v1/lint", "multipart/form-data; boundary=x", []byte("--x--\r\n"), http.StatusBadRequest, "missing multipart field"},
+		{"lint wrong method", http.MethodGet, "/v1/lint", "", nil, http.StatusMethodNotAllowed, `"error"`},
+		{"manifest", http.MethodPost, "/v1/manifest?chart=firefox", "application/yaml", manifest, http.StatusOK, `"findings": []`},
+		{"manifest invalid", http.MethodPost, "/v1/manifest", "application/yaml", []byte("spec:\n  supportArch: [sparc]\n"), http.StatusOK, "OAC-MAN-003"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, resp.StatusCode, data)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" || !json.Valid(data) {
+				t.Errorf("Expected JSON response, got %s: %s", ct, data)
+			}
+			if !strings.Contains(string(data), tt.contains) {
+				t.Errorf("Expected response containing %q, got %s", tt.contains, data)
+			}
+		})
+	}
+}
+
+// TestServerLimits tests the body size, concurrency and timeout limits
+func TestServerLimits(t *testing.T) {
+	archive := tarChart(t, testChart, nil)
+	post := func(h http.Handler, body []byte) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/lint", bytes.NewReader(body)))
+		return rec
+	}
+
+	small := New(&Options{MaxBodyBytes: int64(len(archive)) / 2})
+	if rec := post(small, archive); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for a large body, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body)
+	}
+
+	busy := New(&Options{MaxConcurrent: 1, Timeout: 50 * time.Millisecond})
+	busy.sem <- struct{}{}
+	if rec := post(busy, archive); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d while busy, got %d: %s", http.StatusServiceUnavailable, rec.Code, rec.Body)
+	}
+	<-busy.sem
+	if rec := post(busy, []byte("not a chart")); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d once a slot is free, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body)
+	}
+
+	slow := New(&Options{
+		Timeout: 100 * time.Millisecond,
+		LintOptions: func() *oachecker.LintOptions {
+			return oachecker.DefaultLintOptions().WithContextValidator(func(ctx context.Context, _ string, _ *oachecker.AppConfiguration) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		},
+	})
+	rec := post(slow, archive)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status %d after the timeout, got %d: %s", http.StatusGatewayTimeout, rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), oachecker.RuleLintCanceled) {
+		t.Errorf("Expected a %s finding, got %s", oachecker.RuleLintCanceled, rec.Body)
+	}
+}
+
+// TestServerOptions tests that requests keep the options of the server
+func TestServerOptions(t *testing.T) {
+	s := New(&Options{
+		LintOptions: func() *oachecker.LintOptions {
+			return oachecker.DefaultLintOptions().WithOwner("alice").WithAdmin("bob").DisableRule(oachecker.RuleSupportArch)
+		},
+	})
+	options := s.lintOptions(httptest.NewRequest(http.MethodPost, "/v1/lint", nil))
+	if options.Owner != "alice" || options.Admin != "bob" {
+		t.Errorf("Expected owner/admin alice/bob without parameters, got %s/%s", options.Owner, options.Admin)
+	}
+	options = s.lintOptions(httptest.NewRequest(http.MethodPost, "/v1/lint?owner=carol", nil))
+	if options.Owner != "carol" || options.Admin != "bob" {
+		t.Errorf("Expected owner/admin carol/bob, got %s/%s", options.Owner, options.Admin)
+	}
+
+	manifest := func(h http.Handler, body string) *oachecker.Report {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/manifest", strings.NewReader(body)))
+		var report oachecker.Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Expected a report, got %d: %s", rec.Code, rec.Body)
+		}
+		return &report
+	}
+	body := "spec:\n  supportArch: [sparc]\n"
+	for _, f := range manifest(s, body).Findings {
+		if f.RuleID == oachecker.RuleSupportArch {
+			t.Errorf("Expected %s to be disabled, got %+v", oachecker.RuleSupportArch, f)
+		}
+	}
+	report := manifest(New(nil), "# oachecker:ignore-file OAC-MAN-003 reason=test\n"+body)
+	if len(report.Suppressed) != 1 || report.Suppressed[0].RuleID != oachecker.RuleSupportArch {
+		t.Errorf("Expected the arch finding to be suppressed, got %+v", report.Suppressed)
+	}
+}
+
+// Helper function to package the chart folder at dir like helm package,
+// adding the extra entries as is
+func tarChart(t *testing.T, dir string, extra map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Dir(dir), path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		write(filepath.ToSlash(rel), data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to package chart: %v", err)
+	}
+	for name, data := range extra {
+		write(name, []byte(data))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
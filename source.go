@@ -0,0 +1,147 @@
+package oachecker
+
+import (
+	"bufio"
+	"path"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+const sourceComment = "# Source: "
+
+// renderedDoc is one document of a rendered release manifest together with
+// the template it was rendered from, e.g. firefox/templates/deployment.yaml.
+type renderedDoc struct {
+	source  string
+	content string
+}
+
+// splitRenderedManifest splits a manifest written by helm into its documents,
+// keeping the "# Source:" marker helm puts on top of each of them.
+func splitRenderedManifest(manifest string) []renderedDoc {
+	var docs []renderedDoc
+	var cur *renderedDoc
+	var b strings.Builder
+	flush := func() {
+		if cur != nil {
+			cur.content = b.String()
+			docs = append(docs, *cur)
+		}
+		b.Reset()
+	}
+	scanner := bufio.NewScanner(strings.NewReader(manifest))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimRight(line, " ") == "---" {
+			flush()
+			cur = &renderedDoc{}
+			continue
+		}
+		if cur == nil {
+			cur = &renderedDoc{}
+		}
+		if cur.source == "" && b.Len() == 0 && strings.HasPrefix(line, sourceComment) {
+			cur.source = strings.TrimSpace(strings.TrimPrefix(line, sourceComment))
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	flush()
+	return docs
+}
+
+// templateSources maps the source names helm writes into rendered manifests
+// onto the raw template data of c and its subcharts.
+func templateSources(c *chart.Chart) map[string][]byte {
+	sources := make(map[string][]byte)
+	var walk func(c *chart.Chart, prefix string)
+	walk = func(c *chart.Chart, prefix string) {
+		for _, t := range c.Templates {
+			sources[path.Join(prefix, t.Name)] = t.Data
+		}
+		for _, dep := range c.Dependencies() {
+			walk(dep, path.Join(prefix, "charts", dep.Name()))
+		}
+	}
+	if c != nil {
+		walk(c, c.Name())
+	}
+	return sources
+}
+
+// resourceLocator resolves rendered resources to the template and line they
+// were rendered from.
+type resourceLocator struct {
+	sources map[string][]byte
+	// seen counts the resources of one kind already located per template, as
+	// a template may render several of them.
+	seen map[string]int
+}
+
+func newResourceLocator(c *chart.Chart) *resourceLocator {
+	return &resourceLocator{sources: templateSources(c), seen: make(map[string]int)}
+}
+
+// locate returns the source of a rendered resource in the form
+// templates/deployment.yaml:12, relative to the chart folder. The line is the
+// kind: line of the matching document of the template, or omitted when the
+// template can not be matched.
+func (l *resourceLocator) locate(source, kind string) string {
+	if source == "" {
+		return ""
+	}
+	file := source
+	if idx := strings.Index(source, "/"); idx >= 0 {
+		file = source[idx+1:]
+	}
+	data, ok := l.sources[source]
+	if !ok {
+		return file
+	}
+	key := source + "\x00" + kind
+	n := l.seen[key]
+	l.seen[key]++
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "kind: "+kind || lineIndent(line) != 0 {
+			continue
+		}
+		if n == 0 {
+			return file + ":" + strconv.Itoa(i+1)
+		}
+		n--
+	}
+	return file
+}
+
+// resourceSource splits the source recorded on a rendered resource into the
+// template file and line.
+func resourceSource(r *resource.Info) (string, int) {
+	if r == nil || r.Source == "" {
+		return "", 0
+	}
+	idx := strings.LastIndex(r.Source, ":")
+	if idx < 0 {
+		return r.Source, 0
+	}
+	line, err := strconv.Atoi(r.Source[idx+1:])
+	if err != nil {
+		return r.Source, 0
+	}
+	return r.Source[:idx], line
+}
+
+// withSource attributes f to the rendered resource r and the template it
+// came from.
+func (f *Finding) withSource(r *resource.Info) *Finding {
+	f.withResource(r.Object.GetObjectKind().GroupVersionKind().Kind, r.Name)
+	if file, line := resourceSource(r); file != "" {
+		f.File = file
+		f.Line = line
+	}
+	return f
+}
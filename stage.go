@@ -0,0 +1,235 @@
+package oachecker
+
+import (
+	"context"
+	"fmt"
+)
+
+// names of the lint stages, in the order they run
+const (
+	StageLoad        = "load"
+	StageManifest    = "manifest"
+	StageCustom      = "custom"
+	StageResource    = "resource"
+	StageFolder      = "folder"
+	StageSameVersion = "sameVersion"
+	// StageSuppression is not a stage of its own, it names the rules checking
+	// inline suppression comments after all stages ran.
+	StageSuppression = "suppression"
+	// StageRun names the rules about the lint run itself.
+	StageRun = "run"
+	// StageSubmission names the rules of ValidateSubmission, checking a pull
+	// request to the app store rather than a chart.
+	StageSubmission = "submission"
+)
+
+// lintStage is one step of a lint run. A stage only runs when none of the
+// stages in deps failed; deps that were disabled by options do not block it.
+type lintStage struct {
+	name string
+	deps []string
+	skip func(*LintOptions) bool
+	run  func(*linter) []*Finding
+}
+
+var lintStages = []lintStage{
+	{
+		name: StageLoad,
+		run:  (*linter).loadManifest,
+	},
+	{
+		name: StageManifest,
+		deps: []string{StageLoad},
+		skip: func(o *LintOptions) bool { return o.SkipManifestCheck },
+		run:  func(l *linter) []*Finding { return checkManifest(l.bundle.Manifest) },
+	},
+	{
+		name: StageCustom,
+		deps: []string{StageLoad},
+		run:  (*linter).runCustomValidators,
+	},
+	{
+		name: StageResource,
+		deps: []string{StageLoad},
+		skip: func(o *LintOptions) bool { return o.SkipResourceCheck },
+		run:  func(l *linter) []*Finding { return l.bundle.resourceFindings(l.ctx) },
+	},
+	{
+		name: StageFolder,
+		skip: func(o *LintOptions) bool { return o.SkipFolderCheck },
+		run:  func(l *linter) []*Finding { return lintChartFolder(l.bundle) },
+	},
+	{
+		// CheckSameVersion repeats the folder check, so only run it on a
+		// folder that already passed.
+		name: StageSameVersion,
+		deps: []string{StageFolder},
+		skip: func(o *LintOptions) bool { return o.SkipSameVersionCheck },
+		run:  func(l *linter) []*Finding { return bundleSameVersion(l.bundle) },
+	},
+}
+
+// linter carries the state shared by the stages of one lint run. The chart
+// is read through bundle, so every stage sees the same files and the chart is
+// rendered once.
+type linter struct {
+	ctx     context.Context
+	path    string
+	options *LintOptions
+	bundle  *ChartBundle
+	ran     map[string]bool
+}
+
+func (l *linter) run(report *Report) {
+	l.ran = make(map[string]bool)
+	suppressions := loadSuppressions(l.bundle.FS)
+	baseline := l.options.Baseline.matcher(report.Chart)
+	defer func() {
+		l.report(report, checkSuppressions(suppressions, l.ran, l.options), nil, baseline)
+		stages := map[string]bool{StageSuppression: true}
+		for stage := range l.ran {
+			stages[stage] = true
+		}
+		report.Rules = l.options.checkedRules(stages)
+	}()
+	failed := make(map[string]bool)
+	for i, stage := range lintStages {
+		if stage.skip != nil && stage.skip(l.options) {
+			continue
+		}
+		if f := contextFinding(l.ctx); f != nil {
+			l.cancel(report, f, lintStages[i:])
+			return
+		}
+		if dep := firstFailed(stage.deps, failed); dep != "" {
+			failed[stage.name] = true
+			report.Skipped = append(report.Skipped, SkippedStage{
+				Stage:  stage.name,
+				Reason: fmt.Sprintf("stage %s failed", dep),
+			})
+			continue
+		}
+		findings := l.report(report, l.options.applyRules(stage.run(l)), suppressions, baseline)
+		l.ran[stage.name] = true
+		if f := contextFinding(l.ctx); f != nil {
+			// the stage itself ran, only the ones after it are skipped
+			l.cancel(report, f, lintStages[i+1:])
+			return
+		}
+		if hasErrors(findings) {
+			failed[stage.name] = true
+			if !l.options.CollectAll {
+				return
+			}
+		}
+	}
+}
+
+// report adds findings to report, except the ones silenced by suppressions
+// or the baseline, and returns the findings added.
+func (l *linter) report(report *Report, findings []*Finding, suppressions []*Suppression, baseline *baselineMatcher) []*Finding {
+	l.bundle.locator.annotate(findings)
+	return report.filter(findings, suppressions, baseline)
+}
+
+// filter adds the findings not silenced by suppressions or the baseline to
+// r and returns them.
+func (r *Report) filter(findings []*Finding, suppressions []*Suppression, baseline *baselineMatcher) []*Finding {
+	for _, f := range findings {
+		f.Chart = r.Chart
+	}
+	findings, suppressed := suppress(findings, suppressions)
+	r.Suppressed = append(r.Suppressed, suppressed...)
+	var kept []*Finding
+	for _, f := range findings {
+		if baseline.match(f) {
+			r.Baselined++
+			r.BaselinedFindings = append(r.BaselinedFindings, f)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	r.add(kept...)
+	return kept
+}
+
+// cancel ends a run whose context is done: f is reported as is, bypassing
+// rules, suppressions and the baseline, unless the last stage already
+// reported the cancellation, and the stages not run are skipped.
+func (l *linter) cancel(report *Report, f *Finding, stages []lintStage) {
+	if !hasFinding(report.Findings, RuleLintCanceled) {
+		report.add(f)
+	}
+	for _, stage := range stages {
+		if stage.skip != nil && stage.skip(l.options) {
+			continue
+		}
+		report.Skipped = append(report.Skipped, SkippedStage{Stage: stage.name, Reason: f.Message})
+	}
+}
+
+// contextFinding returns a finding for the error of ctx, nil while ctx is
+// not done.
+func contextFinding(ctx context.Context) *Finding {
+	if ctx == nil || ctx.Err() == nil {
+		return nil
+	}
+	return newFinding(RuleLintCanceled, "", fmt.Errorf("lint canceled: %w", ctx.Err()))
+}
+
+func hasFinding(findings []*Finding, ruleID string) bool {
+	for _, f := range findings {
+		if f.RuleID == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+func firstFailed(deps []string, failed map[string]bool) string {
+	for _, dep := range deps {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
+
+func (l *linter) loadManifest() []*Finding {
+	if _, err := l.bundle.appConfiguration(); err != nil {
+		return []*Finding{newFinding(RuleManifestLoad, ManifestName, err)}
+	}
+	return nil
+}
+
+func (l *linter) runCustomValidators() []*Finding {
+	validators := make([]func(context.Context, string, *AppConfiguration) error, 0,
+		len(l.options.CustomValidators)+len(l.options.ContextValidators))
+	for _, validator := range l.options.CustomValidators {
+		validator := validator
+		validators = append(validators, func(_ context.Context, path string, cfg *AppConfiguration) error {
+			return validator(path, cfg)
+		})
+	}
+	validators = append(validators, l.options.ContextValidators...)
+
+	var findings []*Finding
+	if l.options.AppDataCheck {
+		findings = checkAppData(l.ctx, l.bundle.FS, l.bundle.Manifest)
+		if hasErrors(findings) && !l.options.CollectAll {
+			return findings
+		}
+	}
+	for _, validator := range validators {
+		if l.ctx.Err() != nil {
+			return findings
+		}
+		if err := validator(l.ctx, l.path, l.bundle.Manifest); err != nil {
+			findings = append(findings, asFindings(err, RuleCustomValidator, "")...)
+			if !l.options.CollectAll {
+				return findings
+			}
+		}
+	}
+	return findings
+}
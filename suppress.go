@@ -0,0 +1,216 @@
+package oachecker
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Suppression is an inline comment silencing findings of some rules:
+//
+//	# oachecker:ignore OAC-RES-003 reason=sidecar is sized by the operator
+//	# oachecker:ignore-file OAC-RES-007 reason=shared namespace
+//
+// The first form covers the next YAML node, the second the whole file.
+// Findings on the chart as a whole, which have no file, are not covered by
+// either; disable their rule or record them in a baseline instead.
+type Suppression struct {
+	File    string   `json:"file"`
+	Line    int      `json:"line"`
+	RuleIDs []string `json:"ruleIds"`
+	Reason  string   `json:"reason,omitempty"`
+	// StartLine and EndLine are the lines covered, both 0 for whole-file
+	// suppressions.
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+
+	used bool
+}
+
+var suppressionRegexp = regexp.MustCompile(`#\s*oachecker:(ignore-file|ignore)\b(.*)$`)
+
+// ParseSuppressions returns the suppression comments of a YAML or template
+// file. file is the path relative to the chart folder reported on findings.
+func ParseSuppressions(file string, content []byte) []*Suppression {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	var suppressions []*Suppression
+	for i, line := range lines {
+		m := suppressionRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		s := &Suppression{File: file, Line: i + 1}
+		args := m[2]
+		if idx := strings.Index(args, "reason="); idx >= 0 {
+			s.Reason = strings.TrimSpace(args[idx+len("reason="):])
+			args = args[:idx]
+		}
+		for _, id := range strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			s.RuleIDs = append(s.RuleIDs, id)
+		}
+		if m[1] == "ignore" {
+			s.StartLine, s.EndLine = nodeRange(lines, i+1, isTemplateFile(file))
+		}
+		suppressions = append(suppressions, s)
+	}
+	return suppressions
+}
+
+// nodeRange returns the 1-based line range of the YAML node starting at the
+// first content line at or after index from. A top-level node of a template
+// covers its whole document, so a comment above apiVersion/kind silences the
+// resource.
+func nodeRange(lines []string, from int, wholeDocument bool) (int, int) {
+	start := -1
+	indent := 0
+	startIsKey := false
+	for i := from; i < len(lines); i++ {
+		if skipLine(lines[i]) {
+			continue
+		}
+		trimmed := strings.TrimSpace(lines[i])
+		if start < 0 {
+			start = i
+			indent = lineIndent(lines[i])
+			if strings.HasPrefix(trimmed, "---") {
+				return i + 1, i + 1
+			}
+			startIsKey = !strings.HasPrefix(trimmed, "- ")
+			continue
+		}
+		if strings.HasPrefix(trimmed, "---") {
+			return start + 1, i
+		}
+		if wholeDocument && indent == 0 {
+			continue
+		}
+		lineInd := lineIndent(lines[i])
+		// a sequence may sit at the indentation of the key holding it
+		if lineInd == indent && startIsKey && strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		if lineInd <= indent {
+			return start + 1, i
+		}
+	}
+	if start < 0 {
+		return 0, 0
+	}
+	return start + 1, len(lines)
+}
+
+// skipLine reports lines that do not start or end a node: blanks, comments
+// and lines holding only a template action.
+func skipLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return true
+	}
+	return strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}")
+}
+
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isTemplateFile(file string) bool {
+	return strings.HasPrefix(filepath.ToSlash(file), "templates/")
+}
+
+func (s *Suppression) matches(f *Finding) bool {
+	if !s.covers(f.RuleID) {
+		return false
+	}
+	if f.File == "" || f.File != s.File {
+		return false
+	}
+	if s.StartLine == 0 {
+		return true
+	}
+	return f.Line >= s.StartLine && f.Line <= s.EndLine
+}
+
+func (s *Suppression) covers(ruleID string) bool {
+	for _, id := range s.RuleIDs {
+		if id == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSuppressions reads the suppression comments of OlaresManifest.yaml,
+// Chart.yaml and the files under templates/.
+func loadSuppressions(fsys fs.FS) []*Suppression {
+	var suppressions []*Suppression
+	for _, name := range []string{ManifestName, "Chart.yaml"} {
+		if data, err := fs.ReadFile(fsys, name); err == nil {
+			suppressions = append(suppressions, ParseSuppressions(name, data)...)
+		}
+	}
+	_ = fs.WalkDir(fsys, "templates", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil
+		}
+		suppressions = append(suppressions, ParseSuppressions(name, data)...)
+		return nil
+	})
+	return suppressions
+}
+
+// suppress splits findings into the ones kept and the ones matched by one
+// of suppressions, marking those as used.
+func suppress(findings []*Finding, suppressions []*Suppression) (kept, suppressed []*Finding) {
+	for _, f := range findings {
+		matched := false
+		for _, s := range suppressions {
+			if s.matches(f) {
+				s.used = true
+				matched = true
+			}
+		}
+		if matched {
+			suppressed = append(suppressed, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, suppressed
+}
+
+// checkSuppressions reports suppressions without a reason, and suppressions
+// whose rules ran without producing a matching finding.
+func checkSuppressions(suppressions []*Suppression, ranStages map[string]bool, options *LintOptions) []*Finding {
+	var findings []*Finding
+	for _, s := range suppressions {
+		if s.Reason == "" {
+			findings = append(findings, findingf(RuleSuppressionReason, s.File,
+				"suppression of %s has no reason, add reason=...", strings.Join(s.RuleIDs, ",")).at(s.Line, 0))
+		}
+		if s.used {
+			continue
+		}
+		for _, id := range s.RuleIDs {
+			rule, ok := LookupRule(id)
+			if ok && (!ranStages[rule.Stage] || !options.ruleEnabled(id)) {
+				continue
+			}
+			findings = append(findings, findingf(RuleSuppressionUnused, s.File,
+				"suppression of %s does not match any finding", id).at(s.Line, 0))
+		}
+	}
+	return options.applyRules(findings)
+}